@@ -13,13 +13,17 @@ import (
 )
 
 func (a *App) AgentChat(threadID string, userInput string) error {
+	return a.AgentChatWithAttachments(threadID, userInput, nil)
+}
+
+func (a *App) AgentChatWithAttachments(threadID string, userInput string, inputs []*models.AttachmentInput) error {
 	if a.agentService == nil {
 		return fmt.Errorf("agent service not initialized")
 	}
 	if threadID == "" {
 		return fmt.Errorf("thread ID is required")
 	}
-	if userInput == "" {
+	if userInput == "" && len(inputs) == 0 {
 		return fmt.Errorf("user input is required")
 	}
 
@@ -28,46 +32,23 @@ func (a *App) AgentChat(threadID string, userInput string) error {
 		return err
 	}
 
+	attachments, err := a.agentService.PrepareAttachments(threadID, inputs)
+	if err != nil {
+		return err
+	}
+
 	go func() {
-		msgChan, err := a.agentService.StreamRequestToThread(a.ctx, threadID, userInput)
+		msgChan, err := a.agentService.StreamRequestToThread(a.ctx, threadID, userInput, attachments)
 		if err != nil {
 			runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
-				"type":    "error",
-				"content": fmt.Sprintf("Failed to start agent: %v", err),
+				"thread_id": threadID,
+				"type":      "error",
+				"content":   fmt.Sprintf("Failed to start agent: %v", err),
 			})
 			return
 		}
 
-		var conversationSuccess bool
-		for msg := range msgChan {
-			var content string
-			msgType := string(msg.GetType())
-
-			switch m := msg.(type) {
-			case models.AgentStartThinking:
-				content = ""
-			case models.AgentThought:
-				content = formatThreadMessage(m.Content)
-			case models.AgentExecutingToolStart:
-				payload, _ := json.Marshal(m)
-				content = string(payload)
-			case models.AgentExecutingToolFinish:
-				payload, _ := json.Marshal(m)
-				content = string(payload)
-			case models.AgentFinalResponse:
-				content = formatThreadMessage(m.Content)
-				conversationSuccess = true
-			case models.AgentError:
-				content = formatThreadMessage(m.Error)
-			default:
-				continue
-			}
-
-			runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
-				"type":    msgType,
-				"content": content,
-			})
-		}
+		conversationSuccess := a.forwardAgentMessages(threadID, msgChan)
 
 		if isFirstMessage && conversationSuccess {
 			if err := a.generateAndUpdateThreadTitle(a.ctx, threadID); err != nil {
@@ -104,8 +85,9 @@ func (a *App) EditAndResendMessage(threadID string, userInput string, messageInd
 		msgChan, err := a.agentService.EditAndResendRequestToThread(a.ctx, threadID, messageIndex, userInput)
 		if err != nil {
 			runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
-				"type":    "error",
-				"content": fmt.Sprintf("Failed to edit and resend message: %v", err),
+				"thread_id": threadID,
+				"type":      "error",
+				"content":   fmt.Sprintf("Failed to edit and resend message: %v", err),
 			})
 			return
 		}
@@ -115,36 +97,7 @@ func (a *App) EditAndResendMessage(threadID string, userInput string, messageInd
 			"from_index": messageIndex,
 		})
 
-		var conversationSuccess bool
-		for msg := range msgChan {
-			var content string
-			msgType := string(msg.GetType())
-
-			switch m := msg.(type) {
-			case models.AgentStartThinking:
-				content = ""
-			case models.AgentThought:
-				content = formatThreadMessage(m.Content)
-			case models.AgentExecutingToolStart:
-				payload, _ := json.Marshal(m)
-				content = string(payload)
-			case models.AgentExecutingToolFinish:
-				payload, _ := json.Marshal(m)
-				content = string(payload)
-			case models.AgentFinalResponse:
-				content = formatThreadMessage(m.Content)
-				conversationSuccess = true
-			case models.AgentError:
-				content = formatThreadMessage(m.Error)
-			default:
-				continue
-			}
-
-			runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
-				"type":    msgType,
-				"content": content,
-			})
-		}
+		conversationSuccess := a.forwardAgentMessages(threadID, msgChan)
 
 		if isEditingFirstMessage && conversationSuccess {
 			if err := a.generateAndUpdateThreadTitle(a.ctx, threadID); err != nil {
@@ -185,8 +138,9 @@ func (a *App) RegenerateLastResponse(threadID string) error {
 		msgChan, err := a.agentService.RegenerateLastResponseToThread(a.ctx, threadID)
 		if err != nil {
 			runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
-				"type":    "error",
-				"content": fmt.Sprintf("Failed to regenerate response: %v", err),
+				"thread_id": threadID,
+				"type":      "error",
+				"content":   fmt.Sprintf("Failed to regenerate response: %v", err),
 			})
 			return
 		}
@@ -196,39 +150,154 @@ func (a *App) RegenerateLastResponse(threadID string) error {
 			"from_index": lastUserIndex,
 		})
 
-		for msg := range msgChan {
-			var content string
-			msgType := string(msg.GetType())
-
-			switch m := msg.(type) {
-			case models.AgentStartThinking:
-				content = ""
-			case models.AgentThought:
-				content = formatThreadMessage(m.Content)
-			case models.AgentExecutingToolStart:
-				payload, _ := json.Marshal(m)
-				content = string(payload)
-			case models.AgentExecutingToolFinish:
-				payload, _ := json.Marshal(m)
-				content = string(payload)
-			case models.AgentFinalResponse:
-				content = formatThreadMessage(m.Content)
-			case models.AgentError:
-				content = formatThreadMessage(m.Error)
-			default:
-				continue
-			}
+		a.forwardAgentMessages(threadID, msgChan)
+	}()
 
+	return nil
+}
+
+func (a *App) ContinueThread(threadID string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+
+	go func() {
+		msgChan, err := a.agentService.ContinueThread(a.ctx, threadID)
+		if err != nil {
 			runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
-				"type":    msgType,
-				"content": content,
+				"thread_id": threadID,
+				"type":      "error",
+				"content":   fmt.Sprintf("Failed to continue agent: %v", err),
 			})
+			return
 		}
+
+		a.forwardAgentMessages(threadID, msgChan)
 	}()
 
 	return nil
 }
 
+func (a *App) GetBudgetSettings() (*models.BudgetSettings, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.GetBudgetSettings()
+}
+
+func (a *App) UpdateBudgetSettings(settings *models.BudgetSettings) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.UpdateBudgetSettings(settings)
+}
+
+func (a *App) GetDailyUsage(day string) (*models.AgentUsage, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.GetDailyUsage(day)
+}
+
+func (a *App) forwardAgentMessages(threadID string, msgChan <-chan models.AgentMessage) bool {
+	var conversationSuccess bool
+	for msg := range msgChan {
+		var content string
+		msgType := string(msg.GetType())
+
+		switch m := msg.(type) {
+		case models.AgentStartThinking:
+			content = ""
+		case models.AgentThought:
+			content = formatThreadMessage(m.Content)
+		case models.AgentThoughtDelta:
+			content = m.Content
+		case models.AgentReasoning:
+			content = formatThreadMessage(m.Content)
+		case models.AgentReasoningDelta:
+			content = m.Content
+		case models.AgentStreamDiscarded:
+			content = ""
+		case models.AgentExecutingToolStart:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentExecutingToolFinish:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentContextCompacted:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentToolApprovalRequest:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentRetrying:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentModelSwitched:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentSteered:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentBudgetExceeded:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentHookContext:
+			payload, _ := json.Marshal(m)
+			content = string(payload)
+		case models.AgentFinalResponse:
+			content = formatThreadMessage(m.Content)
+			conversationSuccess = true
+		case models.AgentError:
+			content = formatThreadMessage(m.Error)
+		default:
+			continue
+		}
+
+		runtime.EventsEmit(a.ctx, "agent:message", map[string]string{
+			"thread_id": threadID,
+			"type":      msgType,
+			"content":   content,
+		})
+	}
+
+	return conversationSuccess
+}
+
+func (a *App) SteerThread(threadID string, text string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+	if text == "" {
+		return fmt.Errorf("steering text is required")
+	}
+
+	return a.agentService.SteerThread(threadID, text)
+}
+
+func (a *App) RespondToolApproval(threadID string, callID string, allow bool, remember bool) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+	if callID == "" {
+		return fmt.Errorf("call ID is required")
+	}
+
+	return a.agentService.RespondToolApproval(threadID, callID, allow, remember)
+}
+
 func (a *App) generateAndUpdateThreadTitle(ctx context.Context, threadID string) error {
 	messages, err := a.agentService.GetThreadMessages(threadID)
 	if err != nil {
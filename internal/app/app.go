@@ -5,6 +5,10 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/server"
 	"github.com/zjregee/alter/internal/service"
 )
 
@@ -14,6 +18,9 @@ type App struct {
 
 	threadOrder   []string
 	threadOrderMu sync.RWMutex
+
+	apiServer   *server.Server
+	apiServerMu sync.Mutex
 }
 
 func NewApp() *App {
@@ -28,4 +35,26 @@ func (a *App) Startup(ctx context.Context) {
 
 	a.ctx = ctx
 	a.agentService = agentService
+
+	agentService.OnQueueChanged(func(threadID string, queue []*models.QueuedInput) {
+		runtime.EventsEmit(a.ctx, "thread:queue_changed", map[string]any{
+			"thread_id": threadID,
+			"queue":     queue,
+		})
+	})
+
+	agentService.OnScheduledRun(func(job *models.ScheduledJob, threadID string, msgChan <-chan models.AgentMessage) {
+		runtime.EventsEmit(a.ctx, "schedule:job_started", map[string]string{
+			"job_id":    job.ID,
+			"thread_id": threadID,
+		})
+		a.forwardAgentMessages(threadID, msgChan)
+	})
+	agentService.StartScheduler(ctx)
+}
+
+func (a *App) Shutdown(ctx context.Context) {
+	if a.agentService != nil {
+		a.agentService.StopMCPServers()
+	}
 }
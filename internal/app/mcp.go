@@ -0,0 +1,86 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+func (a *App) GetMCPServers(workspacePath string) (*models.MCPServerSettings, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.GetMCPServers(workspacePath)
+}
+
+func (a *App) UpdateMCPServers(workspacePath string, servers []*models.MCPServerConfig) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.UpdateMCPServers(workspacePath, servers)
+}
+
+func (a *App) GetMCPServerStatuses(workspacePath string) ([]*models.MCPServerStatus, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.GetMCPServerStatuses(workspacePath)
+}
+
+func (a *App) RestartMCPServer(workspacePath string, name string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if name == "" {
+		return fmt.Errorf("MCP server name is required")
+	}
+
+	return a.agentService.RestartMCPServer(a.ctx, workspacePath, name)
+}
+
+func (a *App) ListMCPPrompts(workspacePath string) ([]*models.MCPPrompt, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.ListMCPPrompts(a.ctx, workspacePath)
+}
+
+func (a *App) GetMCPPrompt(workspacePath string, server string, name string, arguments map[string]string) (string, error) {
+	if a.agentService == nil {
+		return "", fmt.Errorf("agent service not initialized")
+	}
+	if server == "" {
+		return "", fmt.Errorf("MCP server name is required")
+	}
+	if name == "" {
+		return "", fmt.Errorf("MCP prompt name is required")
+	}
+
+	return a.agentService.GetMCPPrompt(a.ctx, workspacePath, server, name, arguments)
+}
+
+func (a *App) ListMCPResources(workspacePath string) ([]*models.MCPResource, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.ListMCPResources(a.ctx, workspacePath)
+}
+
+func (a *App) ReadMCPResource(workspacePath string, server string, uri string) (string, error) {
+	if a.agentService == nil {
+		return "", fmt.Errorf("agent service not initialized")
+	}
+	if server == "" {
+		return "", fmt.Errorf("MCP server name is required")
+	}
+	if uri == "" {
+		return "", fmt.Errorf("MCP resource URI is required")
+	}
+
+	return a.agentService.ReadMCPResource(a.ctx, workspacePath, server, uri)
+}
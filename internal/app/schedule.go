@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+func (a *App) ListScheduledJobs() ([]*models.ScheduledJob, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.ListScheduledJobs()
+}
+
+func (a *App) CreateScheduledJob(job *models.ScheduledJob) (*models.ScheduledJob, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	return a.agentService.CreateScheduledJob(job)
+}
+
+func (a *App) UpdateScheduledJob(job *models.ScheduledJob) (*models.ScheduledJob, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if job == nil || job.ID == "" {
+		return nil, fmt.Errorf("scheduled job ID is required")
+	}
+
+	return a.agentService.UpdateScheduledJob(job)
+}
+
+func (a *App) DeleteScheduledJob(jobID string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if jobID == "" {
+		return fmt.Errorf("scheduled job ID is required")
+	}
+
+	return a.agentService.DeleteScheduledJob(jobID)
+}
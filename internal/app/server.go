@@ -0,0 +1,61 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/server"
+)
+
+func (a *App) StartAPIServer(addr string) (*models.APIServerStatus, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+
+	a.apiServerMu.Lock()
+	defer a.apiServerMu.Unlock()
+
+	if a.apiServer == nil {
+		token, err := server.LoadOrCreateToken()
+		if err != nil {
+			return nil, err
+		}
+		a.apiServer = server.New(a.agentService, token)
+	}
+
+	if err := a.apiServer.Start(addr); err != nil {
+		return nil, err
+	}
+
+	return a.apiServerStatusLocked(), nil
+}
+
+func (a *App) StopAPIServer() error {
+	a.apiServerMu.Lock()
+	defer a.apiServerMu.Unlock()
+
+	if a.apiServer == nil {
+		return nil
+	}
+
+	return a.apiServer.Stop()
+}
+
+func (a *App) GetAPIServerStatus() *models.APIServerStatus {
+	a.apiServerMu.Lock()
+	defer a.apiServerMu.Unlock()
+
+	return a.apiServerStatusLocked()
+}
+
+func (a *App) apiServerStatusLocked() *models.APIServerStatus {
+	if a.apiServer == nil || !a.apiServer.Running() {
+		return &models.APIServerStatus{}
+	}
+
+	return &models.APIServerStatus{
+		Running: true,
+		Addr:    a.apiServer.Addr(),
+		Token:   a.apiServer.Token(),
+	}
+}
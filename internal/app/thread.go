@@ -73,6 +73,95 @@ func (a *App) GetThreadMessages(threadID string) ([]*models.ThreadMessage, error
 	return a.agentService.GetThreadMessages(threadID)
 }
 
+func (a *App) GetThreadStats(threadID string) (*models.ThreadStats, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return nil, fmt.Errorf("thread ID is required")
+	}
+
+	return a.agentService.GetThreadStats(threadID)
+}
+
+func (a *App) ListThreadBranches(threadID string, messageIndex int) ([]*models.MessageBranch, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return nil, fmt.Errorf("thread ID is required")
+	}
+	if messageIndex < 0 {
+		return nil, fmt.Errorf("invalid message index")
+	}
+
+	return a.agentService.ListThreadBranches(threadID, messageIndex)
+}
+
+func (a *App) SwitchThreadBranch(threadID string, messageID string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+	if messageID == "" {
+		return fmt.Errorf("message ID is required")
+	}
+
+	return a.agentService.SwitchThreadBranch(threadID, messageID)
+}
+
+func (a *App) GetAttachmentData(threadID string, attachmentID string) (string, error) {
+	if a.agentService == nil {
+		return "", fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return "", fmt.Errorf("thread ID is required")
+	}
+	if attachmentID == "" {
+		return "", fmt.Errorf("attachment ID is required")
+	}
+
+	return a.agentService.GetAttachmentData(threadID, attachmentID)
+}
+
+func (a *App) ListQueuedInputs(threadID string) ([]*models.QueuedInput, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return nil, fmt.Errorf("thread ID is required")
+	}
+
+	return a.agentService.ListQueuedInputs(threadID)
+}
+
+func (a *App) RemoveQueuedInput(threadID string, inputID string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+	if inputID == "" {
+		return fmt.Errorf("input ID is required")
+	}
+
+	return a.agentService.RemoveQueuedInput(threadID, inputID)
+}
+
+func (a *App) CompactThread(threadID string) (*models.AgentContextCompacted, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return nil, fmt.Errorf("thread ID is required")
+	}
+
+	return a.agentService.CompactThread(a.ctx, threadID)
+}
+
 func (a *App) UpdateThreadModel(threadID, modelID string) error {
 	if a.agentService == nil {
 		return fmt.Errorf("agent service not initialized")
@@ -87,6 +176,28 @@ func (a *App) UpdateThreadModel(threadID, modelID string) error {
 	return a.agentService.UpdateThreadModel(threadID, modelID)
 }
 
+func (a *App) UpdateThreadFallbackModels(threadID string, modelIDs []string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+
+	return a.agentService.UpdateThreadFallbackModels(threadID, modelIDs)
+}
+
+func (a *App) UpdateThreadToolOutputLimit(threadID string, limit int) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if threadID == "" {
+		return fmt.Errorf("thread ID is required")
+	}
+
+	return a.agentService.UpdateThreadToolOutputLimit(threadID, limit)
+}
+
 func (a *App) ReorderThreads(order []string) error {
 	if a.agentService == nil {
 		return fmt.Errorf("agent service not initialized")
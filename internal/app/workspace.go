@@ -44,6 +44,94 @@ func (a *App) DeleteWorkspace(workspacePath string) error {
 	return a.agentService.DeleteWorkspace(workspacePath)
 }
 
+func (a *App) GetToolApprovalSettings(workspacePath string) (*models.ToolApprovalSettings, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return nil, fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.GetToolApprovalSettings(workspacePath)
+}
+
+func (a *App) UpdateToolApprovalSettings(workspacePath string, mode string, tools []string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.UpdateToolApprovalSettings(workspacePath, models.ToolApprovalMode(mode), tools)
+}
+
+func (a *App) ClearToolApprovalDecisions(workspacePath string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.ClearToolApprovalDecisions(workspacePath)
+}
+
+func (a *App) GetWorkspaceHooks(workspacePath string) (*models.WorkspaceHooks, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return nil, fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.GetWorkspaceHooks(workspacePath)
+}
+
+func (a *App) UpdateWorkspaceHooks(workspacePath string, hooks []*models.HookConfig) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.UpdateWorkspaceHooks(workspacePath, hooks)
+}
+
+func (a *App) GetBashPolicy(workspacePath string) (*models.BashPolicy, error) {
+	if a.agentService == nil {
+		return nil, fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return nil, fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.GetBashPolicy(workspacePath)
+}
+
+func (a *App) UpdateBashPolicy(workspacePath string, defaultAction string, rules []*models.BashCommandRule, sandbox bool) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.UpdateBashPolicy(workspacePath, models.BashPolicyAction(defaultAction), rules, sandbox)
+}
+
+func (a *App) ResetBashPolicy(workspacePath string) error {
+	if a.agentService == nil {
+		return fmt.Errorf("agent service not initialized")
+	}
+	if workspacePath == "" {
+		return fmt.Errorf("workspace path is required")
+	}
+
+	return a.agentService.ResetBashPolicy(workspacePath)
+}
+
 func (a *App) SelectWorkspace(threadID string) (string, error) {
 	if a.agentService == nil {
 		return "", fmt.Errorf("agent service not initialized")
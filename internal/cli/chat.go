@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service"
+)
+
+const toolPreviewLength = 200
+
+type chatOptions struct {
+	json       bool
+	yes        bool
+	reasoning  bool
+	noTitle    bool
+	stdinLines *bufio.Scanner
+}
+
+func runChat(ctx context.Context, svc *service.AgentService, args []string) error {
+	flags := newFlagSet("chat")
+	opts := &chatOptions{}
+	flags.BoolVar(&opts.json, "json", false, "print every agent message as a JSON line")
+	flags.BoolVar(&opts.yes, "yes", false, "approve every tool call without asking")
+	flags.BoolVar(&opts.reasoning, "reasoning", false, "print model reasoning to stderr")
+	flags.BoolVar(&opts.noTitle, "no-title", false, "do not generate a title after the first message")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+
+	threadID, err := resolveThread(svc, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	opts.stdinLines = bufio.NewScanner(stdin)
+	opts.stdinLines.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	if flags.NArg() > 1 {
+		return sendMessage(ctx, svc, threadID, strings.Join(flags.Args()[1:], " "), opts)
+	}
+
+	for opts.stdinLines.Scan() {
+		line := strings.TrimSpace(opts.stdinLines.Text())
+		if line == "" {
+			continue
+		}
+		if err := sendMessage(ctx, svc, threadID, line, opts); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	return opts.stdinLines.Err()
+}
+
+func sendMessage(ctx context.Context, svc *service.AgentService, threadID string, userInput string, opts *chatOptions) error {
+	isFirstMessage, err := svc.IsFirstMessageToThread(threadID)
+	if err != nil {
+		return err
+	}
+
+	msgChan, err := svc.StreamRequestToThread(ctx, threadID, userInput, nil)
+	if err != nil {
+		return err
+	}
+
+	var runErr error
+	streamed := false
+	for msg := range msgChan {
+		if opts.json {
+			if err := printJSONMessage(msg); err != nil {
+				return err
+			}
+		} else {
+			streamed = printMessage(msg, streamed, opts)
+		}
+
+		switch m := msg.(type) {
+		case models.AgentToolApprovalRequest:
+			allow := opts.yes || (!opts.json && askApproval(m, opts))
+			if err := svc.RespondToolApproval(threadID, m.CallID, allow, false); err != nil {
+				fmt.Fprintf(stderr, "Failed to answer tool approval: %v\n", err)
+			}
+		case models.AgentError:
+			runErr = fmt.Errorf("%s", m.Error)
+		case models.AgentBudgetExceeded:
+			runErr = fmt.Errorf("%s budget exceeded", m.Scope)
+		}
+	}
+	if runErr != nil {
+		return runErr
+	}
+
+	if isFirstMessage && !opts.noTitle {
+		if err := updateThreadTitle(ctx, svc, threadID); err != nil {
+			fmt.Fprintf(stderr, "Failed to generate thread title: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+func printJSONMessage(msg models.AgentMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent message: %w", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		fields = make(map[string]any)
+	}
+	fields["type"] = msg.GetType()
+
+	line, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent message: %w", err)
+	}
+
+	_, err = fmt.Fprintln(stdout, string(line))
+	return err
+}
+
+// printMessage writes answer text to stdout and everything else to stderr, so
+// the output of a scripted chat is just the reply. It returns whether the
+// current answer has been streamed, which makes the final copy redundant.
+func printMessage(msg models.AgentMessage, streamed bool, opts *chatOptions) bool {
+	switch m := msg.(type) {
+	case models.AgentStartThinking:
+		return false
+	case models.AgentThoughtDelta:
+		fmt.Fprint(stdout, m.Content)
+		return true
+	case models.AgentThought:
+		if !streamed {
+			fmt.Fprint(stdout, m.Content)
+		}
+		fmt.Fprintln(stdout)
+		return true
+	case models.AgentReasoningDelta:
+		if opts.reasoning {
+			fmt.Fprint(stderr, m.Content)
+		}
+	case models.AgentStreamDiscarded:
+		if streamed {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintln(stderr, "discarded the partial response above")
+		return false
+	case models.AgentReasoning:
+		if opts.reasoning {
+			fmt.Fprintln(stderr)
+		}
+	case models.AgentExecutingToolStart:
+		fmt.Fprintf(stderr, "-> %s %s\n", m.Name, m.Args)
+	case models.AgentExecutingToolFinish:
+		fmt.Fprintf(stderr, "<- %s %s\n", m.Name, preview(m.Content))
+	case models.AgentContextCompacted:
+		fmt.Fprintf(stderr, "context compacted (%s): %d -> %d tokens\n", m.Strategy, m.TokensBefore, m.TokensAfter)
+	case models.AgentRetrying:
+		fmt.Fprintf(stderr, "retrying (%s) attempt %d/%d in %dms: %s\n", m.Class, m.Attempt, m.MaxRetries, m.DelayMs, m.Error)
+	case models.AgentModelSwitched:
+		fmt.Fprintf(stderr, "switched model %s -> %s (%s)\n", m.From, m.To, m.Class)
+	case models.AgentSteered:
+		fmt.Fprintf(stderr, "steered: %s\n", m.Content)
+	case models.AgentHookContext:
+		fmt.Fprintf(stderr, "%s hook: %s\n", m.Event, m.Content)
+	case models.AgentBudgetExceeded:
+		fmt.Fprintf(stderr, "%s budget exceeded: %s used %g of %g\n", m.Scope, m.Metric, m.Used, m.Limit)
+	case models.AgentFinalResponse:
+		if !streamed {
+			fmt.Fprintln(stdout, m.Content)
+		}
+	case models.AgentError:
+		fmt.Fprintf(stderr, "error: %s\n", m.Error)
+	}
+
+	return streamed
+}
+
+func askApproval(request models.AgentToolApprovalRequest, opts *chatOptions) bool {
+	if request.Reason != "" {
+		fmt.Fprintf(stderr, "%s: %s\n", request.Name, request.Reason)
+	}
+	fmt.Fprintf(stderr, "Allow %s %s? [y/N] ", request.Name, request.Args)
+	if !opts.stdinLines.Scan() {
+		fmt.Fprintln(stderr)
+		return false
+	}
+
+	answer := strings.ToLower(strings.TrimSpace(opts.stdinLines.Text()))
+	return answer == "y" || answer == "yes"
+}
+
+func updateThreadTitle(ctx context.Context, svc *service.AgentService, threadID string) error {
+	messages, err := svc.GetThreadMessages(threadID)
+	if err != nil {
+		return err
+	}
+
+	title, err := service.GenerateThreadTitle(ctx, messages)
+	if err != nil {
+		return err
+	}
+
+	return svc.UpdateThreadTitle(threadID, title)
+}
+
+func preview(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) <= toolPreviewLength {
+		return content
+	}
+
+	return string(runes[:toolPreviewLength]) + "..."
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/zjregee/alter/internal/service"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+const usage = `Usage: alter <command> [arguments]
+
+Commands:
+  threads ls                       List threads
+  threads new [flags]              Create a thread and print its ID
+  threads rm <thread>              Delete a thread
+  threads show <thread>            Print the messages of a thread
+  chat [flags] <thread> [message]  Send a message, or read one message per line from stdin
+  models ls                        List models
+  workspaces ls                    List workspaces
+  workspaces add <path>            Add a workspace
+  serve [flags]                    Serve the local HTTP API until interrupted
+  mcp [flags]                      Serve Alter as an MCP server over stdin and stdout
+
+Threads can be given by ID or by a unique ID prefix.
+Run "alter <command> -h" for the flags of a command.
+`
+
+type command struct {
+	name string
+	run  func(ctx context.Context, svc *service.AgentService, args []string) error
+}
+
+var commands = []command{
+	{name: "threads", run: runThreads},
+	{name: "chat", run: runChat},
+	{name: "models", run: runModels},
+	{name: "workspaces", run: runWorkspaces},
+	{name: "serve", run: runServe},
+	{name: "mcp", run: runMCP},
+}
+
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+var errUsage = errors.New("invalid usage")
+
+// IsCommand reports whether args ask for the CLI rather than the window, so
+// main can choose a mode before starting Wails.
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		return true
+	}
+
+	return slices.ContainsFunc(commands, func(c command) bool {
+		return c.name == args[0]
+	})
+}
+
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+
+	idx := slices.IndexFunc(commands, func(c command) bool {
+		return c.name == args[0]
+	})
+	if idx < 0 {
+		fmt.Fprintf(stderr, "unknown command: %s\n\n%s", args[0], usage)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer func() {
+		if err := storage.Close(); err != nil {
+			fmt.Fprintf(stderr, "Failed to close storage: %v\n", err)
+		}
+	}()
+
+	svc, err := service.NewAgentService(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to initialize agent service: %v\n", err)
+		return 1
+	}
+	defer svc.StopMCPServers()
+
+	if err := commands[idx].run(ctx, svc, args[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "alter %s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
+
+func resolveThread(svc *service.AgentService, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("thread is required")
+	}
+
+	var matches []string
+	for _, info := range svc.ListThreads() {
+		if info.ID == ref {
+			return info.ID, nil
+		}
+		if strings.HasPrefix(info.ID, ref) {
+			matches = append(matches, info.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("thread not found: %s", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		slices.Sort(matches)
+		return "", fmt.Errorf("thread %s is ambiguous: %s", ref, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"context"
+	"os"
+
+	"github.com/zjregee/alter/internal/server"
+	"github.com/zjregee/alter/internal/service"
+)
+
+func runMCP(ctx context.Context, svc *service.AgentService, args []string) error {
+	flags := newFlagSet("mcp")
+	yes := flags.Bool("yes", false, "approve every tool call of a thread instead of denying it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	mcpServer, err := server.NewMCPServer(ctx, svc, *yes)
+	if err != nil {
+		return err
+	}
+
+	// Stdout carries the protocol, so log lines printed by the service have to
+	// go to stderr instead.
+	out := stdout
+	os.Stdout = os.Stderr
+
+	return mcpServer.Serve(ctx, stdin, out)
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/zjregee/alter/internal/service"
+)
+
+func runModels(ctx context.Context, svc *service.AgentService, args []string) error {
+	if len(args) != 1 || args[0] != "ls" {
+		return errUsage
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPROVIDER\tCONTEXT\tVISION")
+	for _, model := range svc.ListModels() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", model.ID, model.Name, model.Provider, model.ContextWindow, model.SupportsVision)
+	}
+
+	return w.Flush()
+}
+
+func runWorkspaces(ctx context.Context, svc *service.AgentService, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "ls":
+		if len(args) != 1 {
+			return errUsage
+		}
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "PATH\tDEFAULT")
+		for _, workspace := range svc.ListWorkspaces() {
+			fmt.Fprintf(w, "%s\t%t\n", workspace.Path, workspace.IsDefault)
+		}
+		return w.Flush()
+	case "add":
+		if len(args) != 2 {
+			return errUsage
+		}
+		path, err := filepath.Abs(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to resolve workspace path: %w", err)
+		}
+		if err := svc.AddWorkspace(path); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, path)
+		return nil
+	default:
+		return errUsage
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/server"
+	"github.com/zjregee/alter/internal/service"
+)
+
+func runServe(ctx context.Context, svc *service.AgentService, args []string) error {
+	flags := newFlagSet("serve")
+	addr := flags.String("addr", server.DefaultAddr, "loopback address to listen on")
+	token := flags.String("token", "", "API token, defaults to the token saved in the data directory")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	if *token == "" {
+		var err error
+		if *token, err = server.LoadOrCreateToken(); err != nil {
+			return err
+		}
+	}
+
+	apiServer := server.New(svc, *token)
+	if err := apiServer.Start(*addr); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stderr, "Listening on http://%s\n", apiServer.Addr())
+	fmt.Fprintf(stderr, "Authorization: Bearer %s\n", apiServer.Token())
+
+	// Jobs scheduled from the window keep running while only the API is served.
+	svc.OnScheduledRun(func(job *models.ScheduledJob, threadID string, msgChan <-chan models.AgentMessage) {
+		fmt.Fprintf(stderr, "Started scheduled job %s in thread %s\n", job.ID, threadID)
+		for range msgChan {
+		}
+	})
+	svc.StartScheduler(ctx)
+
+	<-ctx.Done()
+	return apiServer.Stop()
+}
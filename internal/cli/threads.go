@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service"
+)
+
+func runThreads(ctx context.Context, svc *service.AgentService, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "ls":
+		return listThreads(svc)
+	case "new":
+		return newThread(ctx, svc, args[1:])
+	case "rm":
+		if len(args) != 2 {
+			return errUsage
+		}
+		threadID, err := resolveThread(svc, args[1])
+		if err != nil {
+			return err
+		}
+		return svc.DeleteThread(threadID)
+	case "show":
+		if len(args) != 2 {
+			return errUsage
+		}
+		return showThread(svc, args[1])
+	default:
+		return errUsage
+	}
+}
+
+func listThreads(svc *service.AgentService) error {
+	threads := svc.ListThreads()
+	slices.SortFunc(threads, func(x, y *models.ThreadInfo) int {
+		return cmp.Compare(y.UpdatedAt, x.UpdatedAt)
+	})
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tMODEL\tWORKSPACE\tUPDATED")
+	for _, thread := range threads {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			thread.ID,
+			thread.Title,
+			thread.Model,
+			thread.WorkDir,
+			time.UnixMilli(thread.UpdatedAt).Format(time.DateTime),
+		)
+	}
+
+	return w.Flush()
+}
+
+func newThread(ctx context.Context, svc *service.AgentService, args []string) error {
+	flags := newFlagSet("threads new")
+	modelID := flags.String("model", "", "model ID, defaults to the default model")
+	workDir := flags.String("workspace", "", "workspace path, defaults to the default workspace")
+	title := flags.String("title", "", "thread title")
+	fallback := flags.String("fallback", "", "comma-separated fallback model IDs")
+	toolOutputLimit := flags.Int("tool-output-limit", 0, "tool output size in bytes above which output is saved as an artifact")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	config := svc.DefaultThreadConfig()
+	if *modelID != "" {
+		config.ModelID = *modelID
+	}
+	if *workDir != "" {
+		path, err := filepath.Abs(*workDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve workspace path: %w", err)
+		}
+		config.WorkDir = path
+	}
+	if *fallback != "" {
+		for _, id := range strings.Split(*fallback, ",") {
+			config.FallbackModels = append(config.FallbackModels, strings.TrimSpace(id))
+		}
+	}
+	if *toolOutputLimit < 0 {
+		return fmt.Errorf("tool output limit must be positive: %d", *toolOutputLimit)
+	}
+	config.ToolOutputLimit = *toolOutputLimit
+
+	threadID, err := svc.CreateThreadWithConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	if *title != "" {
+		if err := svc.UpdateThreadTitle(threadID, *title); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(stdout, threadID)
+	return nil
+}
+
+func showThread(svc *service.AgentService, ref string) error {
+	threadID, err := resolveThread(svc, ref)
+	if err != nil {
+		return err
+	}
+
+	messages, err := svc.GetThreadMessages(threadID)
+	if err != nil {
+		return err
+	}
+
+	for i, msg := range messages {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+
+		header := fmt.Sprintf("[%d] %s", i, msg.Role)
+		if msg.Model != "" {
+			header += " (" + msg.Model + ")"
+		}
+		if msg.Steering {
+			header += " steering"
+		}
+		if msg.Hook != "" {
+			header += " hook " + string(msg.Hook)
+		}
+		if msg.BranchCount > 1 {
+			header += fmt.Sprintf(" branch %d/%d", msg.BranchIndex+1, msg.BranchCount)
+		}
+		fmt.Fprintf(stdout, "%s  %s\n", header, time.UnixMilli(msg.Timestamp).Format(time.DateTime))
+
+		for _, attachment := range msg.Attachments {
+			fmt.Fprintf(stdout, "  attachment: %s (%s, %d bytes)\n", attachment.Name, attachment.MIMEType, attachment.Size)
+		}
+		if content := strings.TrimSpace(msg.Content); content != "" {
+			fmt.Fprintln(stdout, content)
+		}
+	}
+
+	return nil
+}
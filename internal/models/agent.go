@@ -9,16 +9,64 @@ type AgentConfig struct {
 	MaxIterations   int
 	RequestInterval time.Duration
 	WorkDir         string
+	ToolOutputLimit int
+	FallbackModels  []string
 }
 
 type AgentUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens       int     `json:"prompt_tokens"`
+	CachedPromptTokens int     `json:"cached_prompt_tokens"`
+	CompletionTokens   int     `json:"completion_tokens"`
+	TotalTokens        int     `json:"total_tokens"`
+	CostUSD            float64 `json:"cost_usd"`
+	Unpriced           bool    `json:"unpriced,omitempty"`
+}
+
+type AgentTurnUsage struct {
+	ModelID            string  `json:"model_id"`
+	PromptTokens       int     `json:"prompt_tokens"`
+	CachedPromptTokens int     `json:"cached_prompt_tokens"`
+	CompletionTokens   int     `json:"completion_tokens"`
+	CostUSD            float64 `json:"cost_usd"`
+	Priced             bool    `json:"priced"`
+	Timestamp          int64   `json:"timestamp"`
 }
 
 type AgentStats struct {
 	Usage               *AgentUsage
+	Turns               []*AgentTurnUsage
 	NextExecutingToolID int64
 	LastRequestTime     time.Time
 }
+
+type ThreadStats struct {
+	ThreadID string            `json:"thread_id"`
+	Usage    *AgentUsage       `json:"usage"`
+	Turns    []*AgentTurnUsage `json:"turns"`
+}
+
+type BudgetScope string
+
+const (
+	BudgetScopeRun    BudgetScope = "run"
+	BudgetScopeThread BudgetScope = "thread"
+	BudgetScopeDay    BudgetScope = "day"
+)
+
+type BudgetMetric string
+
+const (
+	BudgetMetricTokens  BudgetMetric = "tokens"
+	BudgetMetricCostUSD BudgetMetric = "cost_usd"
+)
+
+type BudgetLimit struct {
+	MaxTokens  int     `json:"max_tokens"`
+	MaxCostUSD float64 `json:"max_cost_usd"`
+}
+
+type BudgetSettings struct {
+	Run    BudgetLimit `json:"run"`
+	Thread BudgetLimit `json:"thread"`
+	Day    BudgetLimit `json:"day"`
+}
@@ -5,8 +5,19 @@ type AgentMessageType string
 const (
 	AgentMessageTypeStartThinking       AgentMessageType = "start_thinking"
 	AgentMessageTypeThought             AgentMessageType = "thought"
+	AgentMessageTypeThoughtDelta        AgentMessageType = "thought_delta"
+	AgentMessageTypeReasoning           AgentMessageType = "reasoning"
+	AgentMessageTypeReasoningDelta      AgentMessageType = "reasoning_delta"
+	AgentMessageTypeStreamDiscarded     AgentMessageType = "stream_discarded"
 	AgentMessageTypeExecutingToolStart  AgentMessageType = "executing_tool_start"
 	AgentMessageTypeExecutingToolFinish AgentMessageType = "executing_tool_finish"
+	AgentMessageTypeToolApprovalRequest AgentMessageType = "tool_approval_request"
+	AgentMessageTypeContextCompacted    AgentMessageType = "context_compacted"
+	AgentMessageTypeRetrying            AgentMessageType = "retrying"
+	AgentMessageTypeModelSwitched       AgentMessageType = "model_switched"
+	AgentMessageTypeSteered             AgentMessageType = "steered"
+	AgentMessageTypeBudgetExceeded      AgentMessageType = "budget_exceeded"
+	AgentMessageTypeHookContext         AgentMessageType = "hook_context"
 	AgentMessageTypeFinalResponse       AgentMessageType = "final_response"
 	AgentMessageTypeError               AgentMessageType = "error"
 )
@@ -29,6 +40,39 @@ func (m AgentThought) GetType() AgentMessageType {
 	return AgentMessageTypeThought
 }
 
+type AgentThoughtDelta struct {
+	Content string `json:"content"`
+}
+
+func (m AgentThoughtDelta) GetType() AgentMessageType {
+	return AgentMessageTypeThoughtDelta
+}
+
+type AgentReasoning struct {
+	Content string `json:"content"`
+}
+
+func (m AgentReasoning) GetType() AgentMessageType {
+	return AgentMessageTypeReasoning
+}
+
+type AgentReasoningDelta struct {
+	Content string `json:"content"`
+}
+
+func (m AgentReasoningDelta) GetType() AgentMessageType {
+	return AgentMessageTypeReasoningDelta
+}
+
+// AgentStreamDiscarded follows deltas from a model response that failed
+// partway. Clients drop what was streamed since the last start_thinking, since
+// a retry or another model streams the response again.
+type AgentStreamDiscarded struct{}
+
+func (m AgentStreamDiscarded) GetType() AgentMessageType {
+	return AgentMessageTypeStreamDiscarded
+}
+
 type AgentExecutingToolStart struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -40,16 +84,92 @@ func (m AgentExecutingToolStart) GetType() AgentMessageType {
 }
 
 type AgentExecutingToolFinish struct {
-	ID      int    `json:"id"`
-	Name    string `json:"name"`
-	Args    string `json:"args"`
-	Content string `json:"content"`
+	ID      int         `json:"id"`
+	Name    string      `json:"name"`
+	Args    string      `json:"args"`
+	Content string      `json:"content"`
+	Diffs   []*FileDiff `json:"diffs,omitempty"`
 }
 
 func (m AgentExecutingToolFinish) GetType() AgentMessageType {
 	return AgentMessageTypeExecutingToolFinish
 }
 
+type AgentToolApprovalRequest struct {
+	ID     int         `json:"id"`
+	CallID string      `json:"call_id"`
+	Name   string      `json:"name"`
+	Args   string      `json:"args"`
+	Reason string      `json:"reason,omitempty"`
+	Diffs  []*FileDiff `json:"diffs,omitempty"`
+}
+
+func (m AgentToolApprovalRequest) GetType() AgentMessageType {
+	return AgentMessageTypeToolApprovalRequest
+}
+
+type AgentContextCompacted struct {
+	TokensBefore int                `json:"tokens_before"`
+	TokensAfter  int                `json:"tokens_after"`
+	Strategy     CompactionStrategy `json:"strategy"`
+}
+
+func (m AgentContextCompacted) GetType() AgentMessageType {
+	return AgentMessageTypeContextCompacted
+}
+
+type AgentRetrying struct {
+	Class      ModelErrorClass `json:"class"`
+	Attempt    int             `json:"attempt"`
+	MaxRetries int             `json:"max_retries"`
+	DelayMs    int64           `json:"delay_ms"`
+	Error      string          `json:"error"`
+}
+
+func (m AgentRetrying) GetType() AgentMessageType {
+	return AgentMessageTypeRetrying
+}
+
+type AgentModelSwitched struct {
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Class ModelErrorClass `json:"class"`
+	Error string          `json:"error"`
+}
+
+func (m AgentModelSwitched) GetType() AgentMessageType {
+	return AgentMessageTypeModelSwitched
+}
+
+type AgentSteered struct {
+	Content   string `json:"content"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (m AgentSteered) GetType() AgentMessageType {
+	return AgentMessageTypeSteered
+}
+
+type AgentBudgetExceeded struct {
+	Scope  BudgetScope  `json:"scope"`
+	Metric BudgetMetric `json:"metric"`
+	Limit  float64      `json:"limit"`
+	Used   float64      `json:"used"`
+}
+
+func (m AgentBudgetExceeded) GetType() AgentMessageType {
+	return AgentMessageTypeBudgetExceeded
+}
+
+type AgentHookContext struct {
+	Event   HookEvent `json:"event"`
+	Content string    `json:"content"`
+}
+
+func (m AgentHookContext) GetType() AgentMessageType {
+	return AgentMessageTypeHookContext
+}
+
 type AgentFinalResponse struct {
 	Content string `json:"content"`
 }
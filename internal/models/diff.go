@@ -0,0 +1,38 @@
+package models
+
+type FileDiffOperation string
+
+const (
+	FileDiffOperationCreate FileDiffOperation = "create"
+	FileDiffOperationUpdate FileDiffOperation = "update"
+	FileDiffOperationDelete FileDiffOperation = "delete"
+)
+
+type DiffLineKind string
+
+const (
+	DiffLineContext DiffLineKind = "context"
+	DiffLineAdd     DiffLineKind = "add"
+	DiffLineDelete  DiffLineKind = "delete"
+)
+
+type DiffLine struct {
+	Kind    DiffLineKind `json:"kind"`
+	Content string       `json:"content"`
+}
+
+type DiffHunk struct {
+	OldStart int         `json:"old_start"`
+	OldLines int         `json:"old_lines"`
+	NewStart int         `json:"new_start"`
+	NewLines int         `json:"new_lines"`
+	Lines    []*DiffLine `json:"lines"`
+}
+
+type FileDiff struct {
+	Path      string            `json:"path"`
+	Operation FileDiffOperation `json:"operation"`
+	Additions int               `json:"additions"`
+	Deletions int               `json:"deletions"`
+	Hunks     []*DiffHunk       `json:"hunks"`
+}
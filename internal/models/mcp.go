@@ -0,0 +1,66 @@
+package models
+
+type MCPTransport string
+
+const (
+	MCPTransportStdio MCPTransport = "stdio"
+	MCPTransportHTTP  MCPTransport = "http"
+)
+
+type MCPServerConfig struct {
+	Name      string            `json:"name"`
+	Transport MCPTransport      `json:"transport"`
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Disabled  bool              `json:"disabled,omitempty"`
+}
+
+// MCPServerSettings holds the global servers when WorkspacePath is empty.
+type MCPServerSettings struct {
+	WorkspacePath string             `json:"workspace_path"`
+	Servers       []*MCPServerConfig `json:"servers"`
+}
+
+type MCPServerState string
+
+const (
+	MCPServerStateStopped  MCPServerState = "stopped"
+	MCPServerStateRunning  MCPServerState = "running"
+	MCPServerStateFailed   MCPServerState = "failed"
+	MCPServerStateDisabled MCPServerState = "disabled"
+)
+
+type MCPServerStatus struct {
+	Name      string         `json:"name"`
+	Scope     string         `json:"scope"`
+	State     MCPServerState `json:"state"`
+	Error     string         `json:"error,omitempty"`
+	ToolCount int            `json:"tool_count"`
+	Restarts  int            `json:"restarts"`
+}
+
+type MCPPromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type MCPPrompt struct {
+	Server      string               `json:"server"`
+	Name        string               `json:"name"`
+	Title       string               `json:"title,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Arguments   []*MCPPromptArgument `json:"arguments,omitempty"`
+}
+
+type MCPResource struct {
+	Server      string `json:"server"`
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mime_type,omitempty"`
+}
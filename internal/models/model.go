@@ -1,8 +1,28 @@
 package models
 
 type ModelInfo struct {
-	ID            string `json:"id"`
-	Name          string `json:"name"`
-	Provider      string `json:"provider"`
-	ContextWindow string `json:"context_window"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Provider       string `json:"provider"`
+	ContextWindow  string `json:"context_window"`
+	SupportsVision bool   `json:"supports_vision"`
+}
+
+type ModelErrorClass string
+
+const (
+	ModelErrorClassRateLimit       ModelErrorClass = "rate_limit"
+	ModelErrorClassOverload        ModelErrorClass = "overload"
+	ModelErrorClassNetwork         ModelErrorClass = "network"
+	ModelErrorClassAuth            ModelErrorClass = "auth"
+	ModelErrorClassContextOverflow ModelErrorClass = "context_overflow"
+	ModelErrorClassBadRequest      ModelErrorClass = "bad_request"
+	ModelErrorClassCancelled       ModelErrorClass = "cancelled"
+	ModelErrorClassUnknown         ModelErrorClass = "unknown"
+)
+
+type APIServerStatus struct {
+	Running bool   `json:"running"`
+	Addr    string `json:"addr,omitempty"`
+	Token   string `json:"token,omitempty"`
 }
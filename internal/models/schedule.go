@@ -0,0 +1,33 @@
+package models
+
+type ScheduleKind string
+
+const (
+	ScheduleKindOnce ScheduleKind = "once"
+	ScheduleKindCron ScheduleKind = "cron"
+)
+
+type MissedRunPolicy string
+
+const (
+	MissedRunPolicySkip    MissedRunPolicy = "skip"
+	MissedRunPolicyCatchUp MissedRunPolicy = "catch_up"
+)
+
+type ScheduledJob struct {
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	Prompt          string          `json:"prompt"`
+	ThreadID        string          `json:"thread_id,omitempty"`
+	Kind            ScheduleKind    `json:"kind"`
+	RunAt           int64           `json:"run_at,omitempty"`
+	Cron            string          `json:"cron,omitempty"`
+	MissedRunPolicy MissedRunPolicy `json:"missed_run_policy"`
+	Enabled         bool            `json:"enabled"`
+	NextRunAt       int64           `json:"next_run_at,omitempty"`
+	LastRunAt       int64           `json:"last_run_at,omitempty"`
+	LastThreadID    string          `json:"last_thread_id,omitempty"`
+	LastError       string          `json:"last_error,omitempty"`
+	CreatedAt       int64           `json:"created_at"`
+	UpdatedAt       int64           `json:"updated_at"`
+}
@@ -5,16 +5,75 @@ import (
 )
 
 type ThreadInfo struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Model     string `json:"model"`
-	WorkDir   string `json:"work_dir"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
+	ID              string   `json:"id"`
+	Title           string   `json:"title"`
+	Model           string   `json:"model"`
+	WorkDir         string   `json:"work_dir"`
+	FallbackModels  []string `json:"fallback_models,omitempty"`
+	ToolOutputLimit int      `json:"tool_output_limit,omitempty"`
+	CreatedAt       int64    `json:"created_at"`
+	UpdatedAt       int64    `json:"updated_at"`
 }
 
 type ThreadMessage struct {
-	Role      schema.RoleType `json:"role"`
-	Content   string          `json:"content"`
+	ID          string          `json:"id"`
+	Role        schema.RoleType `json:"role"`
+	Content     string          `json:"content"`
+	Reasoning   string          `json:"reasoning,omitempty"`
+	Model       string          `json:"model,omitempty"`
+	Attachments []*Attachment   `json:"attachments,omitempty"`
+	Timestamp   int64           `json:"timestamp"`
+	Steering    bool            `json:"steering,omitempty"`
+	Hook        HookEvent       `json:"hook,omitempty"`
+	BranchIndex int             `json:"branch_index"`
+	BranchCount int             `json:"branch_count"`
+}
+
+type MessageNode struct {
+	ID        string          `json:"id"`
+	ParentID  string          `json:"parent_id,omitempty"`
+	Message   *schema.Message `json:"message"`
 	Timestamp int64           `json:"timestamp"`
 }
+
+type MessageBranch struct {
+	ID        string `json:"id"`
+	Preview   string `json:"preview"`
+	Timestamp int64  `json:"timestamp"`
+	Active    bool   `json:"active"`
+}
+
+type QueuedInput struct {
+	ID          string        `json:"id"`
+	Content     string        `json:"content"`
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	CreatedAt   int64         `json:"created_at"`
+}
+
+type Attachment struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Size     int    `json:"size"`
+}
+
+type AttachmentInput struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+}
+
+type CompactionStrategy string
+
+const (
+	CompactionStrategyElideToolResults CompactionStrategy = "elide_tool_results"
+	CompactionStrategySummarize        CompactionStrategy = "summarize"
+)
+
+type ThreadCompaction struct {
+	Summary                string `json:"summary"`
+	SummarizedUntil        int    `json:"summarized_until"`
+	ElidedToolResultsUntil int    `json:"elided_tool_results_until"`
+	CompactedAt            int64  `json:"compacted_at"`
+}
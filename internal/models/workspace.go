@@ -4,3 +4,63 @@ type WorkspaceInfo struct {
 	Path      string `json:"path"`
 	IsDefault bool   `json:"is_default"`
 }
+
+type ToolApprovalMode string
+
+const (
+	ToolApprovalModeNever    ToolApprovalMode = "never"
+	ToolApprovalModeSelected ToolApprovalMode = "selected"
+	ToolApprovalModeAlways   ToolApprovalMode = "always"
+)
+
+type ToolApprovalSettings struct {
+	WorkspacePath string           `json:"workspace_path"`
+	Mode          ToolApprovalMode `json:"mode"`
+	Tools         []string         `json:"tools"`
+	Decisions     map[string]bool  `json:"decisions"`
+}
+
+type BashPolicyAction string
+
+const (
+	BashPolicyAllow BashPolicyAction = "allow"
+	BashPolicyAsk   BashPolicyAction = "ask"
+	BashPolicyDeny  BashPolicyAction = "deny"
+)
+
+// BashCommandRule applies to a command by name. A rule with Args only applies
+// when one of the arguments matches one of its patterns, and takes precedence
+// over the rules without Args.
+type BashCommandRule struct {
+	Command string           `json:"command"`
+	Args    []string         `json:"args,omitempty"`
+	Action  BashPolicyAction `json:"action"`
+}
+
+type BashPolicy struct {
+	WorkspacePath string             `json:"workspace_path"`
+	DefaultAction BashPolicyAction   `json:"default_action"`
+	Rules         []*BashCommandRule `json:"rules"`
+	Sandbox       bool               `json:"sandbox,omitempty"`
+}
+
+type HookEvent string
+
+const (
+	HookEventPreToolUse  HookEvent = "PreToolUse"
+	HookEventPostToolUse HookEvent = "PostToolUse"
+	HookEventTurnStart   HookEvent = "TurnStart"
+	HookEventRunFinished HookEvent = "RunFinished"
+)
+
+type HookConfig struct {
+	Event          HookEvent `json:"event"`
+	Tools          []string  `json:"tools,omitempty"`
+	Command        string    `json:"command"`
+	TimeoutSeconds int       `json:"timeout_seconds,omitempty"`
+}
+
+type WorkspaceHooks struct {
+	WorkspacePath string        `json:"workspace_path"`
+	Hooks         []*HookConfig `json:"hooks"`
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service"
+)
+
+const maxRequestBodySize = 32 * 1024 * 1024
+
+type createThreadRequest struct {
+	ModelID         string   `json:"model_id"`
+	WorkDir         string   `json:"work_dir"`
+	Title           string   `json:"title"`
+	FallbackModels  []string `json:"fallback_models"`
+	ToolOutputLimit int      `json:"tool_output_limit"`
+}
+
+type updateThreadRequest struct {
+	ModelID         *string   `json:"model_id"`
+	WorkDir         *string   `json:"work_dir"`
+	Title           *string   `json:"title"`
+	FallbackModels  *[]string `json:"fallback_models"`
+	ToolOutputLimit *int      `json:"tool_output_limit"`
+}
+
+type runRequest struct {
+	Content     string                    `json:"content"`
+	Attachments []*models.AttachmentInput `json:"attachments"`
+}
+
+type approvalRequest struct {
+	Allow    bool `json:"allow"`
+	Remember bool `json:"remember"`
+}
+
+type steerRequest struct {
+	Content string `json:"content"`
+}
+
+type addWorkspaceRequest struct {
+	Path string `json:"path"`
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/models", s.listModels)
+	mux.HandleFunc("GET /v1/workspaces", s.listWorkspaces)
+	mux.HandleFunc("POST /v1/workspaces", s.addWorkspace)
+	mux.HandleFunc("GET /v1/threads", s.listThreads)
+	mux.HandleFunc("POST /v1/threads", s.createThread)
+	mux.HandleFunc("GET /v1/threads/{id}", s.getThread)
+	mux.HandleFunc("PATCH /v1/threads/{id}", s.updateThread)
+	mux.HandleFunc("DELETE /v1/threads/{id}", s.deleteThread)
+	mux.HandleFunc("GET /v1/threads/{id}/messages", s.listMessages)
+	mux.HandleFunc("GET /v1/threads/{id}/stats", s.getThreadStats)
+	mux.HandleFunc("POST /v1/threads/{id}/runs", s.createRun)
+	mux.HandleFunc("POST /v1/threads/{id}/cancel", s.cancelRun)
+	mux.HandleFunc("POST /v1/threads/{id}/steer", s.steerRun)
+	mux.HandleFunc("POST /v1/threads/{id}/approvals/{call_id}", s.respondApproval)
+
+	return s.authorize(mux)
+}
+
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	modelInfos := s.svc.ListModels()
+	slices.SortFunc(modelInfos, func(x, y *models.ModelInfo) int {
+		return strings.Compare(x.ID, y.ID)
+	})
+
+	writeJSON(w, http.StatusOK, modelInfos)
+}
+
+func (s *Server) listWorkspaces(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.svc.ListWorkspaces())
+}
+
+func (s *Server) addWorkspace(w http.ResponseWriter, r *http.Request) {
+	var req addWorkspaceRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("workspace path is required"))
+		return
+	}
+
+	if err := s.svc.AddWorkspace(req.Path); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, s.svc.ListWorkspaces())
+}
+
+func (s *Server) listThreads(w http.ResponseWriter, r *http.Request) {
+	threads := s.svc.ListThreads()
+	slices.SortFunc(threads, func(x, y *models.ThreadInfo) int {
+		return cmp.Compare(y.UpdatedAt, x.UpdatedAt)
+	})
+
+	writeJSON(w, http.StatusOK, threads)
+}
+
+func (s *Server) createThread(w http.ResponseWriter, r *http.Request) {
+	var req createThreadRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	config := s.svc.DefaultThreadConfig()
+	if req.ModelID != "" {
+		config.ModelID = req.ModelID
+	}
+	if req.WorkDir != "" {
+		config.WorkDir = req.WorkDir
+	}
+	config.FallbackModels = req.FallbackModels
+	config.ToolOutputLimit = req.ToolOutputLimit
+
+	threadID, err := s.svc.CreateThreadWithConfig(r.Context(), config)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	if req.Title != "" {
+		if err := s.svc.UpdateThreadTitle(threadID, req.Title); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	}
+
+	s.writeThread(w, http.StatusCreated, threadID)
+}
+
+func (s *Server) getThread(w http.ResponseWriter, r *http.Request) {
+	s.writeThread(w, http.StatusOK, r.PathValue("id"))
+}
+
+func (s *Server) updateThread(w http.ResponseWriter, r *http.Request) {
+	threadID := r.PathValue("id")
+
+	var req updateThreadRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updates := []func() error{}
+	if req.ModelID != nil {
+		updates = append(updates, func() error { return s.svc.UpdateThreadModel(threadID, *req.ModelID) })
+	}
+	if req.FallbackModels != nil {
+		updates = append(updates, func() error { return s.svc.UpdateThreadFallbackModels(threadID, *req.FallbackModels) })
+	}
+	if req.ToolOutputLimit != nil {
+		updates = append(updates, func() error { return s.svc.UpdateThreadToolOutputLimit(threadID, *req.ToolOutputLimit) })
+	}
+	if req.WorkDir != nil {
+		updates = append(updates, func() error { return s.svc.UpdateThreadWorkDir(threadID, *req.WorkDir) })
+	}
+	if req.Title != nil {
+		updates = append(updates, func() error { return s.svc.UpdateThreadTitle(threadID, *req.Title) })
+	}
+
+	for _, update := range updates {
+		if err := update(); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+	}
+
+	s.writeThread(w, http.StatusOK, threadID)
+}
+
+func (s *Server) deleteThread(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.DeleteThread(r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
+	messages, err := s.svc.GetThreadMessages(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, messages)
+}
+
+func (s *Server) getThreadStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.svc.GetThreadStats(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// createRun streams the run as Server-Sent Events named after the
+// AgentMessage types, followed by a final "done" event. Closing the
+// connection cancels the run.
+func (s *Server) createRun(w http.ResponseWriter, r *http.Request) {
+	threadID := r.PathValue("id")
+
+	var req runRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" && len(req.Attachments) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("content is required"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	attachments, err := s.svc.PrepareAttachments(threadID, req.Attachments)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	msgChan, err := s.svc.StreamRequestToThread(r.Context(), threadID, req.Content, attachments)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	writable := true
+	for msg := range msgChan {
+		if !writable {
+			continue
+		}
+		if err := writeEvent(w, string(msg.GetType()), msg); err != nil {
+			writable = false
+			continue
+		}
+		flusher.Flush()
+	}
+
+	if writable {
+		if err := writeEvent(w, "done", struct{}{}); err == nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) cancelRun(w http.ResponseWriter, r *http.Request) {
+	if err := s.svc.CancelStreamRequestToThread(r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) steerRun(w http.ResponseWriter, r *http.Request) {
+	var req steerRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.svc.SteerThread(r.PathValue("id"), req.Content); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) respondApproval(w http.ResponseWriter, r *http.Request) {
+	var req approvalRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := s.svc.RespondToolApproval(r.PathValue("id"), r.PathValue("call_id"), req.Allow, req.Remember); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) writeThread(w http.ResponseWriter, status int, threadID string) {
+	for _, info := range s.svc.ListThreads() {
+		if info.ID == threadID {
+			writeJSON(w, status, info)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, fmt.Errorf("thread not found: %s", threadID))
+}
+
+func readJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("Failed to write API response: %v\n", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrThreadNotFound), errors.Is(err, service.ErrToolApprovalNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrThreadRunning), errors.Is(err, service.ErrThreadNotRunning), errors.Is(err, service.ErrToolApprovalAnswered):
+		writeError(w, http.StatusConflict, err)
+	default:
+		writeError(w, http.StatusBadRequest, err)
+	}
+}
+
+func writeEvent(w io.Writer, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
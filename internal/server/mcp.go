@@ -0,0 +1,308 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service"
+	"github.com/zjregee/alter/internal/service/mcp"
+	skillsService "github.com/zjregee/alter/internal/service/skills"
+	"github.com/zjregee/alter/internal/service/tools"
+	bashtool "github.com/zjregee/alter/internal/service/tools/bash"
+	skillstool "github.com/zjregee/alter/internal/service/tools/skills"
+)
+
+const (
+	sendMessageToolName = "send_message"
+	listThreadsToolName = "list_threads"
+	skillURIPrefix      = "skill://"
+)
+
+const mcpInstructions = "Alter exposes its read-only bash tool, its skill library and its threads. Use send_message to hand a task to an Alter agent and get its final answer."
+
+var mcpServerInfo = mcp.Implementation{Name: "alter", Version: "0.1.0"}
+
+var exposedTools = []string{
+	bashtool.BashToolName,
+	skillstool.ListSkillsToolName,
+	skillstool.LoadSkillToolName,
+}
+
+type sendMessageParams struct {
+	Prompt   string `json:"prompt"`
+	ThreadID string `json:"thread_id,omitempty"`
+	WorkDir  string `json:"work_dir,omitempty"`
+}
+
+type toolWorkDirParams struct {
+	WorkDir string `json:"work_dir,omitempty"`
+}
+
+// NewMCPServer exposes Alter to other MCP clients. Nobody is around to answer
+// tool approvals, so approveTools decides all of them up front.
+func NewMCPServer(ctx context.Context, svc *service.AgentService, approveTools bool) (*mcp.Server, error) {
+	server := mcp.NewServer(mcpServerInfo, mcpInstructions)
+
+	toolInfos, toolsMap, err := tools.GetAllRegisteredTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range toolInfos {
+		if !slices.Contains(exposedTools, info.Name) {
+			continue
+		}
+
+		inputSchema, err := info.ParamsOneOf.ToJSONSchema()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool %s schema: %w", info.Name, err)
+		}
+		data, err := json.Marshal(inputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tool %s schema: %w", info.Name, err)
+		}
+
+		t := toolsMap[info.Name]
+		server.AddTool(mcp.Tool{
+			Name:        info.Name,
+			Description: info.Desc,
+			InputSchema: data,
+		}, func(ctx context.Context, arguments json.RawMessage) (*mcp.CallToolResult, error) {
+			ctx, err := toolContext(ctx, svc, arguments)
+			if err != nil {
+				return nil, err
+			}
+			result, err := t.InvokableRun(ctx, string(arguments))
+			if err != nil {
+				return nil, err
+			}
+			return mcp.TextResult(result), nil
+		})
+	}
+
+	server.AddTool(mcp.Tool{
+		Name:        listThreadsToolName,
+		Description: "Lists Alter threads with their IDs, titles, models and workspaces, most recently updated first.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{}}`),
+	}, func(ctx context.Context, arguments json.RawMessage) (*mcp.CallToolResult, error) {
+		return mcp.TextResult(formatThreads(svc.ListThreads())), nil
+	})
+
+	server.AddTool(mcp.Tool{
+		Name:        sendMessageToolName,
+		Description: "Sends a prompt to an Alter thread, waits for the agent to finish and returns its final response. A new thread is created when thread_id is empty.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "prompt": {"type": "string", "description": "The message to send."},
+    "thread_id": {"type": "string", "description": "The thread to continue. Leave empty to start a new thread."},
+    "work_dir": {"type": "string", "description": "The workspace of a new thread. Defaults to the default workspace."}
+  },
+  "required": ["prompt"]
+}`),
+	}, func(ctx context.Context, arguments json.RawMessage) (*mcp.CallToolResult, error) {
+		var params sendMessageParams
+		if err := json.Unmarshal(arguments, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse arguments: %w", err)
+		}
+		return sendMessage(ctx, svc, &params, approveTools)
+	})
+
+	server.SetPrompts(&mcp.PromptProvider{
+		List: listSkillPrompts,
+		Get:  getSkillPrompt,
+	})
+	server.SetResources(&mcp.ResourceProvider{
+		List: listSkillResources,
+		Read: readSkillResource,
+	})
+
+	return server, nil
+}
+
+func sendMessage(ctx context.Context, svc *service.AgentService, params *sendMessageParams, approveTools bool) (*mcp.CallToolResult, error) {
+	prompt := strings.TrimSpace(params.Prompt)
+	if prompt == "" {
+		return nil, fmt.Errorf("prompt must be provided")
+	}
+
+	threadID := strings.TrimSpace(params.ThreadID)
+	if threadID == "" {
+		config := svc.DefaultThreadConfig()
+		if params.WorkDir != "" {
+			config.WorkDir = params.WorkDir
+		}
+
+		var err error
+		if threadID, err = svc.CreateThreadWithConfig(ctx, config); err != nil {
+			return nil, err
+		}
+	}
+
+	msgChan, err := svc.StreamRequestToThread(ctx, threadID, prompt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var content string
+	var runErr error
+	for msg := range msgChan {
+		switch m := msg.(type) {
+		case models.AgentToolApprovalRequest:
+			if err := svc.RespondToolApproval(threadID, m.CallID, approveTools, false); err != nil {
+				fmt.Printf("Failed to answer tool approval: %v\n", err)
+			}
+		case models.AgentFinalResponse:
+			content = m.Content
+		case models.AgentError:
+			runErr = fmt.Errorf("thread %s: %s", threadID, m.Error)
+		case models.AgentBudgetExceeded:
+			runErr = fmt.Errorf("thread %s: %s budget exceeded: %s used %g of %g", threadID, m.Scope, m.Metric, m.Used, m.Limit)
+		}
+	}
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	return mcp.TextResult(fmt.Sprintf("%s\n\nThread: %s", content, threadID)), nil
+}
+
+// toolContext gives a served tool the workspace it runs in: the registered
+// workspace that contains its work_dir argument, or the default workspace.
+func toolContext(ctx context.Context, svc *service.AgentService, arguments json.RawMessage) (context.Context, error) {
+	var params toolWorkDirParams
+	_ = json.Unmarshal(arguments, &params)
+
+	workDir := strings.TrimSpace(params.WorkDir)
+	workspace := ""
+	for _, info := range svc.ListWorkspaces() {
+		switch {
+		case !filepath.IsAbs(workDir):
+			if info.IsDefault {
+				workspace = info.Path
+			}
+		case tools.IsWithin(info.Path, workDir) && len(info.Path) > len(workspace):
+			workspace = info.Path
+		}
+	}
+	if workspace == "" {
+		if filepath.IsAbs(workDir) {
+			return nil, fmt.Errorf("work_dir is not inside a registered workspace: %s", workDir)
+		}
+		return ctx, nil
+	}
+
+	policy, err := svc.GetBashPolicy(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	return bashtool.WithPolicy(tools.WithWorkDir(ctx, workspace), policy), nil
+}
+
+func formatThreads(threads []*models.ThreadInfo) string {
+	if len(threads) == 0 {
+		return "Threads: (empty)"
+	}
+
+	slices.SortFunc(threads, func(x, y *models.ThreadInfo) int {
+		return cmp.Compare(y.UpdatedAt, x.UpdatedAt)
+	})
+
+	var b strings.Builder
+	fmt.Fprint(&b, "Threads:")
+	for _, thread := range threads {
+		fmt.Fprintf(&b, "\n- ID: %s\n  Title: %s\n  Model: %s\n  Workspace: %s", thread.ID, thread.Title, thread.Model, thread.WorkDir)
+	}
+
+	return b.String()
+}
+
+func listSkillPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	summaries, err := skillsService.LoadAllSkillSummaries()
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := make([]mcp.Prompt, 0, len(summaries))
+	for _, summary := range summaries {
+		prompts = append(prompts, mcp.Prompt{
+			Name:        summary.Name,
+			Description: summary.Description,
+		})
+	}
+
+	return prompts, nil
+}
+
+func getSkillPrompt(ctx context.Context, name string, arguments map[string]string) (*mcp.GetPromptResult, error) {
+	skill, err := findSkill(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.GetPromptResult{
+		Description: skill.Summary.Description,
+		Messages: []mcp.PromptMessage{{
+			Role:    "user",
+			Content: mcp.Content{Type: "text", Text: skill.Content},
+		}},
+	}, nil
+}
+
+func listSkillResources(ctx context.Context) ([]mcp.Resource, error) {
+	summaries, err := skillsService.LoadAllSkillSummaries()
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]mcp.Resource, 0, len(summaries))
+	for _, summary := range summaries {
+		resources = append(resources, mcp.Resource{
+			URI:         skillURIPrefix + summary.Name,
+			Name:        summary.Name,
+			Description: summary.Description,
+			MIMEType:    "text/markdown",
+		})
+	}
+
+	return resources, nil
+}
+
+func readSkillResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	name, ok := strings.CutPrefix(uri, skillURIPrefix)
+	if !ok {
+		return nil, fmt.Errorf("resource not found: %s", uri)
+	}
+
+	skill, err := findSkill(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return []mcp.ResourceContents{{
+		URI:      uri,
+		MIMEType: "text/markdown",
+		Text:     skill.Content,
+	}}, nil
+}
+
+func findSkill(name string) (*models.SkillContent, error) {
+	contents, err := skillsService.LoadAllSkillContents()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, content := range contents {
+		if content != nil && content.Summary != nil && strings.EqualFold(content.Summary.Name, name) {
+			return content, nil
+		}
+	}
+
+	return nil, fmt.Errorf("skill not found: %s", name)
+}
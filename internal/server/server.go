@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zjregee/alter/internal/service"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+const (
+	DefaultAddr     = "127.0.0.1:7878"
+	shutdownTimeout = 5 * time.Second
+)
+
+type Server struct {
+	svc   *service.AgentService
+	token string
+
+	mu       sync.Mutex
+	http     *http.Server
+	listener net.Listener
+}
+
+func New(svc *service.AgentService, token string) *Server {
+	return &Server{
+		svc:   svc,
+		token: token,
+	}
+}
+
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// LoadOrCreateToken keeps one token across restarts so scripts do not need to
+// be reconfigured every time the app starts.
+func LoadOrCreateToken() (string, error) {
+	token, err := storage.LoadAPIToken()
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		return token, nil
+	}
+
+	token, err = GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	if err := storage.SaveAPIToken(token); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Start listens on a loopback address only; the token guards against other
+// local users, not against the network.
+func (s *Server) Start(addr string) error {
+	if s.token == "" {
+		return fmt.Errorf("API token is required")
+	}
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	if err := checkLoopback(addr); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.http != nil {
+		return fmt.Errorf("API server is already running on %s", s.listener.Addr())
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.http = server
+	s.listener = listener
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Failed to serve API: %v\n", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return ""
+	}
+
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Token() string {
+	return s.token
+}
+
+func (s *Server) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.http != nil
+}
+
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	server := s.http
+	s.http = nil
+	s.listener = nil
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		return server.Close()
+	}
+
+	return nil
+}
+
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %w", addr, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	return fmt.Errorf("API server must listen on a loopback address, got %s", host)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLocalHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host not allowed: %s", r.Host))
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing API token"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLocalHost rejects requests whose Host header names another site, which is
+// what a DNS rebinding page in a browser would send.
+func isLocalHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
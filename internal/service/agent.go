@@ -4,7 +4,9 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -13,6 +15,10 @@ import (
 
 	"github.com/zjregee/alter/internal/models"
 	"github.com/zjregee/alter/internal/service/tools"
+	_ "github.com/zjregee/alter/internal/service/tools/artifacts"
+	bashtool "github.com/zjregee/alter/internal/service/tools/bash"
+	_ "github.com/zjregee/alter/internal/service/tools/files"
+	_ "github.com/zjregee/alter/internal/service/tools/skills"
 )
 
 //go:embed assets/prompts/agent.txt
@@ -21,6 +27,7 @@ var promptContent []byte
 const (
 	defaultMaxIterations   = 40
 	defaultRequestInterval = 3 * time.Second
+	defaultToolOutputLimit = 16 * 1024
 )
 
 type Agent struct {
@@ -29,11 +36,27 @@ type Agent struct {
 	tools    []*schema.ToolInfo
 	toolsMap map[string]tool.InvokableTool
 
+	builtinTools    []*schema.ToolInfo
+	builtinToolsMap map[string]tool.InvokableTool
+
+	mu                sync.RWMutex
 	messages          []*schema.Message
 	messageTimestamps []int64
+	messageIDs        []string
+	nodes             map[string]*models.MessageNode
+	children          map[string][]*models.MessageNode
 	stats             *models.AgentStats
+	compaction        *models.ThreadCompaction
+
+	cancelFunc        context.CancelFunc
+	pendingSteering   []steeringNote
+	acceptingSteering bool
+	modelIndex        int
+	runUsage          models.AgentUsage
+	budgetWaived      bool
 
-	cancelFunc context.CancelFunc
+	approvalsMu      sync.Mutex
+	pendingApprovals map[string]*pendingApproval
 }
 
 func applyDefaults(c *models.AgentConfig) error {
@@ -43,7 +66,7 @@ func applyDefaults(c *models.AgentConfig) error {
 	if c.ModelID == "" {
 		return fmt.Errorf("agent model is required")
 	}
-	if isModelAvailable(c.ModelID) {
+	if !isModelAvailable(c.ModelID) {
 		return fmt.Errorf("agent model is not available: %s", c.ModelID)
 	}
 	if c.MaxIterations <= 0 {
@@ -52,10 +75,18 @@ func applyDefaults(c *models.AgentConfig) error {
 	if c.RequestInterval <= 0 {
 		c.RequestInterval = defaultRequestInterval
 	}
+	if c.ToolOutputLimit <= 0 {
+		c.ToolOutputLimit = defaultToolOutputLimit
+	}
+	fallbackModels, err := normalizeFallbackModels(c.ModelID, c.FallbackModels)
+	if err != nil {
+		return err
+	}
+	c.FallbackModels = fallbackModels
 	if c.WorkDir == "" {
 		return fmt.Errorf("agent work dir is required")
 	}
-	if isWorkspacePathAvailable(c.WorkDir) {
+	if !isWorkspacePathAvailable(c.WorkDir) {
 		return fmt.Errorf("agent work dir is not available: %s", c.WorkDir)
 	}
 
@@ -79,27 +110,31 @@ func NewAgent(ctx context.Context, cfg models.AgentConfig) (*Agent, error) {
 		return nil, err
 	}
 
-	return &Agent{
-		id:       GenerateAgentID(),
-		config:   cfg,
-		tools:    toolInfos,
-		toolsMap: toolsMap,
-		messages: []*schema.Message{
-			{
-				Role:    schema.System,
-				Content: buildSystemPrompt(cfg.WorkDir),
-			},
-		},
-		messageTimestamps: []int64{time.Now().UnixMilli()},
+	agent := &Agent{
+		id:              GenerateAgentID(),
+		config:          cfg,
+		tools:           toolInfos,
+		toolsMap:        toolsMap,
+		builtinTools:    toolInfos,
+		builtinToolsMap: toolsMap,
+		nodes:           make(map[string]*models.MessageNode),
+		children:        make(map[string][]*models.MessageNode),
 		stats: &models.AgentStats{
 			Usage:               &models.AgentUsage{},
 			NextExecutingToolID: 0,
 			LastRequestTime:     time.Now(),
 		},
-	}, nil
+		pendingApprovals: make(map[string]*pendingApproval),
+	}
+	agent.appendMessage(&schema.Message{
+		Role:    schema.System,
+		Content: buildSystemPrompt(cfg.WorkDir),
+	})
+
+	return agent, nil
 }
 
-func NewAgentWithMessages(ctx context.Context, id string, cfg models.AgentConfig, messages []*schema.Message, messageTimestamps []int64, stats *models.AgentStats) (*Agent, error) {
+func NewAgentWithMessageTree(ctx context.Context, id string, cfg models.AgentConfig, nodes []*models.MessageNode, activeLeafID string, stats *models.AgentStats, compaction *models.ThreadCompaction) (*Agent, error) {
 	if err := applyDefaults(&cfg); err != nil {
 		return nil, err
 	}
@@ -109,15 +144,35 @@ func NewAgentWithMessages(ctx context.Context, id string, cfg models.AgentConfig
 		return nil, err
 	}
 
-	return &Agent{
-		id:                id,
-		config:            cfg,
-		tools:             toolInfos,
-		toolsMap:          toolsMap,
-		messages:          messages,
-		messageTimestamps: messageTimestamps,
-		stats:             stats,
-	}, nil
+	agent := &Agent{
+		id:               id,
+		config:           cfg,
+		tools:            toolInfos,
+		toolsMap:         toolsMap,
+		builtinTools:     toolInfos,
+		builtinToolsMap:  toolsMap,
+		nodes:            make(map[string]*models.MessageNode, len(nodes)),
+		children:         make(map[string][]*models.MessageNode),
+		stats:            stats,
+		compaction:       compaction,
+		pendingApprovals: make(map[string]*pendingApproval),
+	}
+	for _, node := range nodes {
+		if node == nil || node.Message == nil {
+			continue
+		}
+		agent.nodes[node.ID] = node
+		agent.children[node.ParentID] = append(agent.children[node.ParentID], node)
+	}
+	for _, siblings := range agent.children {
+		slices.SortFunc(siblings, compareMessageNodes)
+	}
+
+	if err := agent.setActivePathLocked(activeLeafID); err != nil {
+		return nil, fmt.Errorf("failed to restore agent %s messages: %w", id, err)
+	}
+
+	return agent, nil
 }
 
 func (a *Agent) ID() string {
@@ -138,11 +193,36 @@ func (a *Agent) UpdateModelID(modelID string) error {
 		return fmt.Errorf("agent model is required")
 	}
 
-	if isModelAvailable(modelID) {
+	if !isModelAvailable(modelID) {
 		return fmt.Errorf("agent model is not available: %s", modelID)
 	}
 
+	fallbackModels, err := normalizeFallbackModels(modelID, a.config.FallbackModels)
+	if err != nil {
+		return err
+	}
+
 	a.config.ModelID = modelID
+	a.config.FallbackModels = fallbackModels
+	return nil
+}
+
+func (a *Agent) UpdateFallbackModels(modelIDs []string) error {
+	fallbackModels, err := normalizeFallbackModels(a.config.ModelID, modelIDs)
+	if err != nil {
+		return err
+	}
+
+	a.config.FallbackModels = fallbackModels
+	return nil
+}
+
+func (a *Agent) UpdateToolOutputLimit(limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("tool output limit must be positive: %d", limit)
+	}
+
+	a.config.ToolOutputLimit = limit
 	return nil
 }
 
@@ -155,6 +235,9 @@ func (a *Agent) UpdateWorkDir(workDir string) error {
 	if workDir == a.config.WorkDir {
 		return nil
 	}
+	if !isWorkspacePathAvailable(workDir) {
+		return fmt.Errorf("agent work dir is not available: %s", workDir)
+	}
 
 	if len(a.messages) > 1 {
 		return fmt.Errorf("agent messages are not empty")
@@ -163,73 +246,120 @@ func (a *Agent) UpdateWorkDir(workDir string) error {
 	a.config.WorkDir = workDir
 	a.messages[0].Content = buildSystemPrompt(workDir)
 	a.messageTimestamps[0] = time.Now().UnixMilli()
+	a.nodes[a.messageIDs[0]].Timestamp = a.messageTimestamps[0]
+	slices.SortFunc(a.children[""], compareMessageNodes)
 	return nil
 }
 
-func (a *Agent) StreamRequest(ctx context.Context, userInput string) <-chan models.AgentMessage {
+func (a *Agent) StreamRequest(ctx context.Context, userInput string, attachments []*models.Attachment) <-chan models.AgentMessage {
+	return a.startLoop(ctx, buildUserMessage(userInput, attachments))
+}
+
+func (a *Agent) StreamRegenerate(ctx context.Context) <-chan models.AgentMessage {
+	return a.startLoop(ctx, nil)
+}
+
+func (a *Agent) StreamContinue(ctx context.Context) <-chan models.AgentMessage {
+	a.mu.Lock()
+	a.budgetWaived = true
+	a.mu.Unlock()
+
+	return a.startLoop(ctx, nil)
+}
+
+func (a *Agent) startLoop(ctx context.Context, userMessage *schema.Message) <-chan models.AgentMessage {
+	loopChan := make(chan models.AgentMessage)
 	msgChan := make(chan models.AgentMessage)
 
 	streamCtx, cancel := context.WithCancel(ctx)
+	a.mu.Lock()
 	a.cancelFunc = cancel
+	a.mu.Unlock()
+	a.openSteering()
 
-	go a.reActLoop(streamCtx, userInput, msgChan)
+	go a.reActLoop(streamCtx, userMessage, loopChan)
+	go a.forwardRun(loopChan, msgChan)
 
 	return msgChan
 }
 
 func (a *Agent) CancelStreamRequest() {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	if a.cancelFunc != nil {
 		a.cancelFunc()
 	}
 }
 
+func (a *Agent) IsRunning() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.cancelFunc != nil
+}
+
 func (a *Agent) GetMessagesWithTimestamps() ([]*schema.Message, []int64) {
-	return a.messages, a.messageTimestamps
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return slices.Clone(a.messages), slices.Clone(a.messageTimestamps)
 }
 
-func (a *Agent) TruncateMessagesSince(index int) error {
-	nonSystemIndex := -1
-	actualIndex := -1
+func (a *Agent) appendMessage(msg *schema.Message) {
+	a.appendMessageAt(msg, time.Now().UnixMilli())
+}
 
-	for i, msg := range a.messages {
-		if msg.Role != schema.System {
-			nonSystemIndex += 1
-			if nonSystemIndex == index {
-				actualIndex = i
-				break
-			}
-		}
-	}
+func (a *Agent) appendMessageAt(msg *schema.Message, timestamp int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	if actualIndex == -1 {
-		return fmt.Errorf("invalid message index: %d", index)
+	node := &models.MessageNode{
+		ID:        GenerateMessageID(),
+		Message:   msg,
+		Timestamp: timestamp,
+	}
+	if len(a.messageIDs) > 0 {
+		node.ParentID = a.messageIDs[len(a.messageIDs)-1]
 	}
 
-	a.messages = a.messages[:actualIndex+1]
-	a.messageTimestamps = a.messageTimestamps[:actualIndex+1]
-
-	return nil
+	a.addNodeLocked(node)
+	a.messages = append(a.messages, msg)
+	a.messageTimestamps = append(a.messageTimestamps, timestamp)
+	a.messageIDs = append(a.messageIDs, node.ID)
 }
 
-func (a *Agent) reActLoop(ctx context.Context, userInput string, msgChan chan models.AgentMessage) {
+func (a *Agent) reActLoop(ctx context.Context, userMessage *schema.Message, msgChan chan models.AgentMessage) {
 	defer close(msgChan)
 	defer func() {
+		// Notes sent after the last turn started would be lost when the run
+		// stops early, so they are kept in the thread for the next run.
+		a.mu.Lock()
+		a.acceptingSteering = false
+		a.mu.Unlock()
+		a.injectSteering(msgChan)
+
+		a.mu.Lock()
 		if a.cancelFunc != nil {
+			a.cancelFunc()
 			a.cancelFunc = nil
 		}
+		a.budgetWaived = false
+		a.mu.Unlock()
 	}()
 
-	if strings.TrimSpace(userInput) == "" {
-		msgChan <- models.AgentError{Error: "user input is empty"}
-		return
-	}
-
-	userMessage := &schema.Message{
-		Role:    schema.User,
-		Content: userInput,
+	if userMessage != nil {
+		if strings.TrimSpace(userMessage.Content) == "" && len(userMessage.UserInputMultiContent) == 0 {
+			msgChan <- models.AgentError{Error: "user input is empty"}
+			return
+		}
+		a.appendMessage(userMessage)
 	}
-	a.messages = append(a.messages, userMessage)
-	a.messageTimestamps = append(a.messageTimestamps, time.Now().UnixMilli())
+	a.mu.Lock()
+	a.modelIndex = 0
+	a.runUsage = models.AgentUsage{}
+	a.mu.Unlock()
+	a.refreshTools(ctx)
 
 	iterations := 0
 	for iterations < a.config.MaxIterations {
@@ -240,35 +370,56 @@ func (a *Agent) reActLoop(ctx context.Context, userInput string, msgChan chan mo
 		default:
 		}
 
+		a.injectSteering(msgChan)
+
+		exceeded, err := a.checkBudgets()
+		if err != nil {
+			fmt.Printf("Failed to check agent %s budgets: %v\n", a.id, err)
+		} else if exceeded != nil {
+			msgChan <- *exceeded
+			return
+		}
+
+		turnStart := a.runHooks(ctx, hookInput{
+			Event:     models.HookEventTurnStart,
+			Iteration: iterations + 1,
+		})
+		a.injectHookContext(models.HookEventTurnStart, turnStart.context, msgChan)
+
+		compacted, err := a.maybeCompact(ctx)
+		if err != nil {
+			fmt.Printf("Failed to compact agent %s messages: %v\n", a.id, err)
+		} else if compacted != nil {
+			msgChan <- *compacted
+		}
+
 		a.waitForNextTurn()
 		msgChan <- models.AgentStartThinking{}
 
-		response, err := a.generate(ctx)
+		response, err := a.generateWithFallback(ctx, msgChan)
 		if err != nil {
-			if strings.Contains(err.Error(), "429") {
-				time.Sleep(3 * time.Second)
-				iterations += 1
-				continue
-			}
 			msgChan <- models.AgentError{Error: fmt.Sprintf("agent generation failed: %v", err)}
 			return
 		}
 
-		if response.ResponseMeta != nil && response.ResponseMeta.Usage != nil {
-			usage := response.ResponseMeta.Usage
-			a.stats.Usage.PromptTokens += usage.PromptTokens
-			a.stats.Usage.CompletionTokens += usage.CompletionTokens
-			a.stats.Usage.TotalTokens = a.stats.Usage.PromptTokens + a.stats.Usage.CompletionTokens
+		a.recordUsage(messageModel(response), response)
+
+		if response.ReasoningContent != "" {
+			msgChan <- models.AgentReasoning{Content: response.ReasoningContent}
 		}
 
 		if response.Content != "" {
 			msgChan <- models.AgentThought{Content: response.Content}
 		}
 
-		a.messages = append(a.messages, response)
-		a.messageTimestamps = append(a.messageTimestamps, time.Now().UnixMilli())
+		a.appendMessage(response)
 
 		if len(response.ToolCalls) == 0 {
+			if !a.closeSteeringIfIdle() {
+				iterations += 1
+				continue
+			}
+
 			content := response.Content
 			if content == "" {
 				content = "Sorry, I couldn't generate a meaningful response."
@@ -278,31 +429,80 @@ func (a *Agent) reActLoop(ctx context.Context, userInput string, msgChan chan mo
 		}
 
 		type toolResult struct {
-			call   schema.ToolCall
-			result string
-			err    error
+			call    schema.ToolCall
+			result  string
+			err     error
+			context string
 		}
 
 		toolResultChan := make(chan toolResult, len(response.ToolCalls))
 		for _, toolCall := range response.ToolCalls {
 			go func(tc schema.ToolCall) {
 				toolID := int(atomic.AddInt64(&a.stats.NextExecutingToolID, 1))
+				pre := a.runHooks(ctx, hookInput{
+					Event:         models.HookEventPreToolUse,
+					ToolName:      tc.Function.Name,
+					ToolCallID:    tc.ID,
+					ToolArguments: tc.Function.Arguments,
+				})
+				if pre.arguments != "" {
+					tc.Function.Arguments = pre.arguments
+				}
+				hookContext := pre.context
+
 				msgChan <- models.AgentExecutingToolStart{
 					ID:   toolID,
 					Name: tc.Function.Name,
 					Args: tc.Function.Arguments,
 				}
-				result, err := a.invokeTool(ctx, tc)
+				var result string
+				var diffs []*models.FileDiff
+				var err error
+				if pre.blocked {
+					result = formatHookBlockedResult(pre.reason)
+				} else {
+					var allowed, bashApproved bool
+					allowed, bashApproved, err = a.awaitToolApproval(ctx, toolID, tc, msgChan)
+					if err == nil {
+						if allowed {
+							toolCtx := ctx
+							if bashApproved {
+								toolCtx = bashtool.WithApproval(ctx)
+							}
+							result, diffs, err = a.invokeTool(toolCtx, tc)
+							if err == nil {
+								result = a.spillToolOutput(tc, result)
+							}
+						} else {
+							result = deniedToolCallResult
+						}
+					}
+
+					input := hookInput{
+						Event:         models.HookEventPostToolUse,
+						ToolName:      tc.Function.Name,
+						ToolCallID:    tc.ID,
+						ToolArguments: tc.Function.Arguments,
+						ToolResult:    result,
+					}
+					if err != nil {
+						input.ToolError = err.Error()
+					}
+					post := a.runHooks(ctx, input)
+					hookContext = append(hookContext, post.context...)
+				}
 				msgChan <- models.AgentExecutingToolFinish{
 					ID:      toolID,
 					Name:    tc.Function.Name,
 					Args:    tc.Function.Arguments,
 					Content: result,
+					Diffs:   diffs,
 				}
 				toolResultChan <- toolResult{
-					call:   tc,
-					result: result,
-					err:    err,
+					call:    tc,
+					result:  result,
+					err:     err,
+					context: formatHookContext(hookContext),
 				}
 			}(toolCall)
 		}
@@ -319,12 +519,14 @@ func (a *Agent) reActLoop(ctx context.Context, userInput string, msgChan chan mo
 				if res.err != nil {
 					content = fmt.Sprintf("Tool %s call failed: %v", tc.Function.Name, res.err)
 				}
-				a.messages = append(a.messages, &schema.Message{
+				if res.context != "" {
+					content += "\n\n" + res.context
+				}
+				a.appendMessage(&schema.Message{
 					Role:       schema.Tool,
 					ToolCallID: tc.ID,
 					Content:    content,
 				})
-				a.messageTimestamps = append(a.messageTimestamps, time.Now().UnixMilli())
 			}
 		}
 
@@ -353,33 +555,80 @@ func (a *Agent) waitForNextTurn() {
 	a.stats.LastRequestTime = time.Now()
 }
 
-func (a *Agent) generate(ctx context.Context) (*schema.Message, error) {
-	model, err := getModel(ctx, a.config.ModelID)
+func (a *Agent) generate(ctx context.Context, modelID string, msgChan chan<- models.AgentMessage) (*schema.Message, error) {
+	model, err := getModel(ctx, modelID, a.config.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	toolInfos := a.tools
+	a.mu.RUnlock()
+
+	modelWithTools, err := model.WithTools(toolInfos)
 	if err != nil {
 		return nil, err
 	}
 
-	modelWithTools, err := model.WithTools(a.tools)
+	stream, err := modelWithTools.Stream(ctx, a.resolveAttachments(a.promptMessagesFor(modelID), modelID))
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := modelWithTools.Generate(ctx, a.messages)
+	response, err := receiveStream(stream, msgChan)
 	if err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
-func (a *Agent) invokeTool(ctx context.Context, toolCall schema.ToolCall) (string, error) {
+func (a *Agent) invokeTool(ctx context.Context, toolCall schema.ToolCall) (string, []*models.FileDiff, error) {
+	a.mu.RLock()
 	targetTool, exists := a.toolsMap[toolCall.Function.Name]
+	a.mu.RUnlock()
 	if !exists {
-		return "", fmt.Errorf("agent tool not found: %s", toolCall.Function.Name)
+		return "", nil, fmt.Errorf("agent tool not found: %s", toolCall.Function.Name)
+	}
+
+	if toolCall.Function.Name == bashtool.BashToolName {
+		policy, err := getBashPolicy(a.config.WorkDir)
+		if err != nil {
+			return "", nil, err
+		}
+		ctx = bashtool.WithPolicy(ctx, policy)
+	}
+
+	// Tools that touch the disk refuse to run without a workspace, which is
+	// what happens once the thread's workspace is no longer registered.
+	if isWorkspacePathAvailable(a.config.WorkDir) {
+		ctx = tools.WithWorkDir(ctx, a.config.WorkDir)
 	}
 
+	ctx, changes := tools.WithFileChanges(tools.WithThreadID(ctx, a.id), false)
 	result, err := targetTool.InvokableRun(ctx, toolCall.Function.Arguments)
 	if err != nil {
-		return "", err
+		return "", changes.Diffs(), err
+	}
+	return result, changes.Diffs(), nil
+}
+
+// previewToolCall dry-runs a tool that supports it, so an approval request can
+// show the changes it would make.
+func (a *Agent) previewToolCall(ctx context.Context, toolCall schema.ToolCall) []*models.FileDiff {
+	if !tools.IsPreviewTool(toolCall.Function.Name) {
+		return nil
+	}
+
+	a.mu.RLock()
+	targetTool, exists := a.toolsMap[toolCall.Function.Name]
+	a.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	ctx, changes := tools.WithFileChanges(tools.WithWorkDir(ctx, a.config.WorkDir), true)
+	if _, err := targetTool.InvokableRun(ctx, toolCall.Function.Arguments); err != nil {
+		return nil
 	}
-	return result, nil
+	return changes.Diffs()
 }
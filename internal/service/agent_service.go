@@ -19,23 +19,53 @@ const defaultThreadTitle = "New chat"
 type AgentService struct {
 	agents map[string]*Thread
 	mu     sync.RWMutex
+
+	queueChangedHandler func(threadID string, queue []*models.QueuedInput)
+
+	scheduler *scheduler
 }
 
 type Thread struct {
 	Info  *models.ThreadInfo
 	Agent *Agent
+
+	running bool
+	queue   []*threadRun
+}
+
+type threadRun struct {
+	input      *models.QueuedInput
+	regenerate bool
+	resume     bool
+	ctx        context.Context
+	outChan    chan models.AgentMessage
+}
+
+func (t *Thread) queuedInputsLocked() []*models.QueuedInput {
+	inputs := make([]*models.QueuedInput, 0, len(t.queue))
+	for _, run := range t.queue {
+		inputs = append(inputs, run.input)
+	}
+
+	return inputs
 }
 
 func newDefaultAgentConfig() models.AgentConfig {
-	return models.AgentConfig{
-		ModelID: getDefaultModelInfo().ID,
-		WorkDir: getDefaultWorkspace().Path,
+	var config models.AgentConfig
+	if info := getDefaultModelInfo(); info != nil {
+		config.ModelID = info.ID
+	}
+	if workspace := getDefaultWorkspace(); workspace != nil {
+		config.WorkDir = workspace.Path
 	}
+
+	return config
 }
 
 func NewAgentService(ctx context.Context) (*AgentService, error) {
 	service := &AgentService{
-		agents: make(map[string]*Thread),
+		agents:    make(map[string]*Thread),
+		scheduler: newScheduler(),
 	}
 
 	if err := service.loadThreadsFromStorage(ctx); err != nil {
@@ -61,8 +91,27 @@ func (s *AgentService) DeleteWorkspace(workspacePath string) error {
 	return deleteWorkspace(workspacePath)
 }
 
+func (s *AgentService) GetToolApprovalSettings(workspacePath string) (*models.ToolApprovalSettings, error) {
+	return getToolApprovalSettings(workspacePath)
+}
+
+func (s *AgentService) UpdateToolApprovalSettings(workspacePath string, mode models.ToolApprovalMode, tools []string) error {
+	return updateToolApprovalSettings(workspacePath, mode, tools)
+}
+
+func (s *AgentService) ClearToolApprovalDecisions(workspacePath string) error {
+	return clearToolApprovalDecisions(workspacePath)
+}
+
+func (s *AgentService) DefaultThreadConfig() models.AgentConfig {
+	return newDefaultAgentConfig()
+}
+
 func (s *AgentService) CreateThread(ctx context.Context) (string, error) {
-	config := newDefaultAgentConfig()
+	return s.CreateThreadWithConfig(ctx, newDefaultAgentConfig())
+}
+
+func (s *AgentService) CreateThreadWithConfig(ctx context.Context, config models.AgentConfig) (string, error) {
 	agent, err := NewAgent(ctx, config)
 	if err != nil {
 		return "", err
@@ -70,17 +119,19 @@ func (s *AgentService) CreateThread(ctx context.Context) (string, error) {
 
 	thread := &Thread{
 		Info: &models.ThreadInfo{
-			ID:        agent.ID(),
-			Title:     defaultThreadTitle,
-			Model:     agent.Config().ModelID,
-			WorkDir:   agent.Config().WorkDir,
-			CreatedAt: time.Now().UnixMilli(),
-			UpdatedAt: time.Now().UnixMilli(),
+			ID:              agent.ID(),
+			Title:           defaultThreadTitle,
+			Model:           agent.Config().ModelID,
+			WorkDir:         agent.Config().WorkDir,
+			FallbackModels:  agent.Config().FallbackModels,
+			ToolOutputLimit: agent.Config().ToolOutputLimit,
+			CreatedAt:       time.Now().UnixMilli(),
+			UpdatedAt:       time.Now().UnixMilli(),
 		},
 		Agent: agent,
 	}
 
-	if err := s.persistThread(thread); err != nil {
+	if err := saveThread(thread); err != nil {
 		return "", err
 	}
 
@@ -104,117 +155,115 @@ func (s *AgentService) ListThreads() []*models.ThreadInfo {
 }
 
 func (s *AgentService) DeleteThread(id string) error {
-	s.mu.RLock()
-	_, exists := s.agents[id]
-	s.mu.RUnlock()
-
+	// The thread leaves the map before its files are deleted, so a run that
+	// finishes in between cannot save it again.
+	s.mu.Lock()
+	thread, exists := s.agents[id]
 	if !exists {
 		s.mu.Unlock()
-		return fmt.Errorf("thread not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
+	for _, run := range thread.queue {
+		close(run.outChan)
+	}
+	thread.queue = nil
+	thread.Agent.CancelStreamRequest()
+	delete(s.agents, id)
+	s.mu.Unlock()
 
 	if err := storage.DeleteThread(id); err != nil {
 		return err
 	}
-
-	s.mu.Lock()
-	delete(s.agents, id)
-	s.mu.Unlock()
+	if err := storage.DeleteThreadArtifacts(id); err != nil {
+		fmt.Printf("Failed to delete artifacts of thread %s: %v\n", id, err)
+	}
+	if err := storage.DeleteThreadAttachments(id); err != nil {
+		fmt.Printf("Failed to delete attachments of thread %s: %v\n", id, err)
+	}
 
 	return nil
 }
 
-func (s *AgentService) StreamRequestToThread(ctx context.Context, id string, userInput string) (<-chan models.AgentMessage, error) {
-	s.mu.RLock()
-	_, exists := s.agents[id]
-	s.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("thread not found: %s", id)
-	}
-
+func (s *AgentService) StreamRequestToThread(ctx context.Context, id string, userInput string, attachments []*models.Attachment) (<-chan models.AgentMessage, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	current, found := s.agents[id]
 	if !found {
-		return nil, fmt.Errorf("thread not found: %s", id)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 
-	current.Info.UpdatedAt = time.Now().UnixMilli()
-	originChan := current.Agent.StreamRequest(ctx, userInput)
+	run := &threadRun{
+		input: &models.QueuedInput{
+			ID:          GenerateQueuedInputID(),
+			Content:     userInput,
+			Attachments: attachments,
+			CreatedAt:   time.Now().UnixMilli(),
+		},
+		ctx:     ctx,
+		outChan: make(chan models.AgentMessage),
+	}
 
-	outChan := make(chan models.AgentMessage)
-	go func(thread *Thread) {
-		for msg := range originChan {
-			outChan <- msg
-		}
+	if !current.running {
+		current.running = true
+		s.startRunLocked(current, run)
+		s.mu.Unlock()
+		return run.outChan, nil
+	}
 
-		close(outChan)
+	current.queue = append(current.queue, run)
+	queue := current.queuedInputsLocked()
+	s.mu.Unlock()
 
-		if err := s.persistThread(thread); err != nil {
-			fmt.Printf("Failed to persist thread %s: %v\n", thread.Info.ID, err)
-		}
-	}(current)
+	s.notifyQueueChanged(id, queue)
 
-	return outChan, nil
+	return run.outChan, nil
 }
 
 func (s *AgentService) EditAndResendRequestToThread(ctx context.Context, id string, messageIndex int, userInput string) (<-chan models.AgentMessage, error) {
-	s.mu.RLock()
-	_, exists := s.agents[id]
-	s.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("thread not found: %s", id)
-	}
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	current, found := s.agents[id]
 	if !found {
-		return nil, fmt.Errorf("thread not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 
-	if err := current.Agent.TruncateMessagesSince(messageIndex); err != nil {
-		return nil, fmt.Errorf("failed to truncate thread messages since index %d: %w", messageIndex, err)
+	if current.running {
+		return nil, fmt.Errorf("%w: %s", ErrThreadRunning, id)
 	}
 
-	current.Info.UpdatedAt = time.Now().UnixMilli()
-	originChan := current.Agent.StreamRequest(ctx, userInput)
-
-	outChan := make(chan models.AgentMessage)
-	go func(thread *Thread) {
-		for msg := range originChan {
-			outChan <- msg
-		}
+	if err := current.Agent.RewindBefore(messageIndex); err != nil {
+		return nil, fmt.Errorf("failed to branch thread messages at index %d: %w", messageIndex, err)
+	}
 
-		close(outChan)
+	run := &threadRun{
+		input: &models.QueuedInput{
+			ID:        GenerateQueuedInputID(),
+			Content:   userInput,
+			CreatedAt: time.Now().UnixMilli(),
+		},
+		ctx:     ctx,
+		outChan: make(chan models.AgentMessage),
+	}
 
-		if err := s.persistThread(thread); err != nil {
-			fmt.Printf("Failed to persist thread %s: %v\n", thread.Info.ID, err)
-		}
-	}(current)
+	current.running = true
+	s.startRunLocked(current, run)
 
-	return outChan, nil
+	return run.outChan, nil
 }
 
 func (s *AgentService) RegenerateLastResponseToThread(ctx context.Context, id string) (<-chan models.AgentMessage, error) {
-	s.mu.RLock()
-	_, exists := s.agents[id]
-	s.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("thread not found: %s", id)
-	}
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	current, found := s.agents[id]
 	if !found {
-		return nil, fmt.Errorf("thread not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	if current.running {
+		return nil, fmt.Errorf("%w: %s", ErrThreadRunning, id)
 	}
 
 	messages, _ := current.Agent.GetMessagesWithTimestamps()
@@ -228,10 +277,9 @@ func (s *AgentService) RegenerateLastResponseToThread(ctx context.Context, id st
 			continue
 		}
 		nonSystemIndex += 1
-		if msg.Role == schema.User {
+		if msg.Role == schema.User && !isInjectedMessage(msg) {
 			lastUserMessage = msg
 			lastUserIndex = nonSystemIndex
-			break
 		}
 	}
 
@@ -239,91 +287,351 @@ func (s *AgentService) RegenerateLastResponseToThread(ctx context.Context, id st
 		return nil, fmt.Errorf("no user message found to regenerate from")
 	}
 
-	userContent := lastUserMessage.Content
+	if err := current.Agent.RewindThrough(lastUserIndex); err != nil {
+		return nil, fmt.Errorf("failed to branch thread messages at index %d: %w", lastUserIndex, err)
+	}
 
-	if err := current.Agent.TruncateMessagesSince(lastUserIndex); err != nil {
-		return nil, fmt.Errorf("failed to truncate thread messages since index %d: %w", lastUserIndex, err)
+	run := &threadRun{
+		input: &models.QueuedInput{
+			ID:          GenerateQueuedInputID(),
+			Content:     displayContent(lastUserMessage),
+			Attachments: messageAttachments(lastUserMessage),
+			CreatedAt:   time.Now().UnixMilli(),
+		},
+		regenerate: true,
+		ctx:        ctx,
+		outChan:    make(chan models.AgentMessage),
 	}
 
-	current.Info.UpdatedAt = time.Now().UnixMilli()
-	originChan := current.Agent.StreamRequest(ctx, userContent)
+	current.running = true
+	s.startRunLocked(current, run)
 
-	outChan := make(chan models.AgentMessage)
-	go func(thread *Thread) {
-		for msg := range originChan {
-			outChan <- msg
+	return run.outChan, nil
+}
+
+func (s *AgentService) ContinueThread(ctx context.Context, id string) (<-chan models.AgentMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, found := s.agents[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	if current.running {
+		return nil, fmt.Errorf("%w: %s", ErrThreadRunning, id)
+	}
+
+	messages, _ := current.Agent.GetMessagesWithTimestamps()
+	if len(messages) == 0 || messages[len(messages)-1].Role == schema.System {
+		return nil, fmt.Errorf("no run to continue in thread: %s", id)
+	}
+
+	run := &threadRun{
+		input: &models.QueuedInput{
+			ID:        GenerateQueuedInputID(),
+			CreatedAt: time.Now().UnixMilli(),
+		},
+		resume:  true,
+		ctx:     ctx,
+		outChan: make(chan models.AgentMessage),
+	}
+
+	current.running = true
+	s.startRunLocked(current, run)
+
+	return run.outChan, nil
+}
+
+func (s *AgentService) CancelStreamRequestToThread(id string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current, found := s.agents[id]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	current.Agent.CancelStreamRequest()
+	return nil
+}
+
+func (s *AgentService) SteerThread(id string, text string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current, found := s.agents[id]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	if !current.running {
+		return fmt.Errorf("%w: %s", ErrThreadNotRunning, id)
+	}
+
+	return current.Agent.Steer(text)
+}
+
+func (s *AgentService) ListQueuedInputs(id string) ([]*models.QueuedInput, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current, found := s.agents[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	return current.queuedInputsLocked(), nil
+}
+
+func (s *AgentService) RemoveQueuedInput(id string, inputID string) error {
+	s.mu.Lock()
+
+	current, found := s.agents[id]
+	if !found {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	removed := false
+	for i, run := range current.queue {
+		if run.input.ID == inputID {
+			current.queue = append(current.queue[:i], current.queue[i+1:]...)
+			close(run.outChan)
+			removed = true
+			break
 		}
+	}
+
+	queue := current.queuedInputsLocked()
+	s.mu.Unlock()
+
+	if !removed {
+		return fmt.Errorf("queued input not found: %s", inputID)
+	}
+
+	s.notifyQueueChanged(id, queue)
+
+	return nil
+}
+
+func (s *AgentService) OnQueueChanged(handler func(threadID string, queue []*models.QueuedInput)) {
+	s.mu.Lock()
+	s.queueChangedHandler = handler
+	s.mu.Unlock()
+}
+
+func (s *AgentService) notifyQueueChanged(threadID string, queue []*models.QueuedInput) {
+	s.mu.RLock()
+	handler := s.queueChangedHandler
+	s.mu.RUnlock()
+
+	if handler != nil {
+		handler(threadID, queue)
+	}
+}
 
-		close(outChan)
+func (s *AgentService) startRunLocked(thread *Thread, run *threadRun) {
+	thread.Info.UpdatedAt = time.Now().UnixMilli()
+	var originChan <-chan models.AgentMessage
+	if run.regenerate {
+		originChan = thread.Agent.StreamRegenerate(run.ctx)
+	} else if run.resume {
+		originChan = thread.Agent.StreamContinue(run.ctx)
+	} else {
+		originChan = thread.Agent.StreamRequest(run.ctx, run.input.Content, run.input.Attachments)
+	}
+
+	go func() {
+		for msg := range originChan {
+			run.outChan <- msg
+		}
 
 		if err := s.persistThread(thread); err != nil {
 			fmt.Printf("Failed to persist thread %s: %v\n", thread.Info.ID, err)
 		}
-	}(current)
 
-	return outChan, nil
+		s.mu.RLock()
+		_, exists := s.agents[thread.Info.ID]
+		s.mu.RUnlock()
+
+		close(run.outChan)
+
+		if !exists {
+			return
+		}
+
+		s.finishRun(thread)
+	}()
 }
 
-func (s *AgentService) CancelStreamRequestToThread(id string) error {
+func (s *AgentService) finishRun(thread *Thread) {
+	s.mu.Lock()
+
+	var next *threadRun
+	for len(thread.queue) > 0 {
+		candidate := thread.queue[0]
+		thread.queue = thread.queue[1:]
+		if candidate.ctx.Err() != nil {
+			close(candidate.outChan)
+			continue
+		}
+		next = candidate
+		break
+	}
+
+	if next == nil {
+		thread.running = false
+		s.mu.Unlock()
+		return
+	}
+
+	s.startRunLocked(thread, next)
+	queue := thread.queuedInputsLocked()
+	s.mu.Unlock()
+
+	s.notifyQueueChanged(thread.Info.ID, queue)
+}
+
+func (s *AgentService) RespondToolApproval(id string, callID string, allow bool, remember bool) error {
 	s.mu.RLock()
-	_, exists := s.agents[id]
+	thread, exists := s.agents[id]
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("thread not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	toolName, err := thread.Agent.RespondToolApproval(callID, allow)
+	if err != nil {
+		return err
+	}
 
-	current, found := s.agents[id]
-	if !found {
-		return fmt.Errorf("thread not found: %s", id)
+	if remember {
+		if err := rememberToolApprovalDecision(thread.Agent.Config().WorkDir, toolName, allow); err != nil {
+			return fmt.Errorf("failed to remember tool approval: %w", err)
+		}
 	}
 
-	current.Agent.CancelStreamRequest()
 	return nil
 }
 
+func (s *AgentService) CompactThread(ctx context.Context, id string) (*models.AgentContextCompacted, error) {
+	s.mu.RLock()
+	thread, exists := s.agents[id]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	s.mu.RLock()
+	running := thread.running
+	s.mu.RUnlock()
+
+	if running {
+		return nil, fmt.Errorf("%w: %s", ErrThreadRunning, id)
+	}
+
+	compacted, err := thread.Agent.Compact(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact thread: %w", err)
+	}
+
+	s.mu.Lock()
+	thread.Info.UpdatedAt = time.Now().UnixMilli()
+	s.mu.Unlock()
+
+	if err := s.persistThread(thread); err != nil {
+		return nil, err
+	}
+
+	return compacted, nil
+}
+
 func (s *AgentService) GetThreadMessages(id string) ([]*models.ThreadMessage, error) {
 	s.mu.RLock()
 	thread, exists := s.agents[id]
 	s.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("thread not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 
-	msgs, timestamps := thread.Agent.GetMessagesWithTimestamps()
+	path := thread.Agent.GetActivePath()
 	messages := make([]*models.ThreadMessage, 0)
-	for i, msg := range msgs {
+	for _, node := range path {
+		msg := node.Message
 		if msg.Role == schema.System {
 			continue
 		}
 
+		branchIndex, branchCount := thread.Agent.BranchPosition(node.ID)
 		messages = append(messages, &models.ThreadMessage{
-			Role:      msg.Role,
-			Content:   msg.Content,
-			Timestamp: timestamps[i],
+			ID:          node.ID,
+			Role:        msg.Role,
+			Content:     displayContent(msg),
+			Reasoning:   msg.ReasoningContent,
+			Model:       messageModel(msg),
+			Attachments: messageAttachments(msg),
+			Timestamp:   node.Timestamp,
+			Steering:    isSteeringMessage(msg),
+			Hook:        messageHookEvent(msg),
+			BranchIndex: branchIndex,
+			BranchCount: branchCount,
 		})
 	}
 
 	return messages, nil
 }
 
+func (s *AgentService) ListThreadBranches(id string, messageIndex int) ([]*models.MessageBranch, error) {
+	s.mu.RLock()
+	thread, exists := s.agents[id]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	return thread.Agent.ListBranches(messageIndex)
+}
+
+func (s *AgentService) SwitchThreadBranch(id string, messageID string) error {
+	s.mu.Lock()
+	current, found := s.agents[id]
+	if !found {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+	if current.running {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrThreadRunning, id)
+	}
+
+	if err := current.Agent.SwitchBranch(messageID); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to switch thread branch: %w", err)
+	}
+	current.Info.UpdatedAt = time.Now().UnixMilli()
+	s.mu.Unlock()
+
+	return s.persistThread(current)
+}
+
 func (s *AgentService) IsFirstMessageToThread(id string) (bool, error) {
 	s.mu.RLock()
 	_, exists := s.agents[id]
 	s.mu.RUnlock()
 
 	if !exists {
-		return false, fmt.Errorf("thread not found: %s", id)
+		return false, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 
 	s.mu.RLock()
 	current, found := s.agents[id]
 	if !found {
-		return false, fmt.Errorf("thread not found: %s", id)
+		return false, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 	messages, _ := current.Agent.GetMessagesWithTimestamps()
 	s.mu.RUnlock()
@@ -340,57 +648,71 @@ func (s *AgentService) IsFirstMessageToThread(id string) (bool, error) {
 }
 
 func (s *AgentService) UpdateThreadModel(id string, modelID string) error {
-	s.mu.RLock()
-	thread, exists := s.agents[id]
-	s.mu.RUnlock()
+	return s.updateIdleThread(id, func(thread *Thread) error {
+		if err := thread.Agent.UpdateModelID(modelID); err != nil {
+			return fmt.Errorf("failed to update thread model: %w", err)
+		}
 
-	if !exists {
-		return fmt.Errorf("thread not found: %s", id)
-	}
+		thread.Info.Model = thread.Agent.Config().ModelID
+		thread.Info.FallbackModels = thread.Agent.Config().FallbackModels
+		return nil
+	})
+}
 
-	if err := thread.Agent.UpdateModelID(modelID); err != nil {
-		return fmt.Errorf("failed to update thread model: %w", err)
-	}
+func (s *AgentService) UpdateThreadFallbackModels(id string, modelIDs []string) error {
+	return s.updateIdleThread(id, func(thread *Thread) error {
+		if err := thread.Agent.UpdateFallbackModels(modelIDs); err != nil {
+			return fmt.Errorf("failed to update thread fallback models: %w", err)
+		}
 
-	s.mu.Lock()
-	current, found := s.agents[id]
-	if found {
-		current.Info.Model = modelID
-		current.Info.UpdatedAt = time.Now().UnixMilli()
-	}
-	s.mu.Unlock()
+		thread.Info.FallbackModels = thread.Agent.Config().FallbackModels
+		return nil
+	})
+}
 
-	if !found {
-		return fmt.Errorf("thread not found: %s", id)
-	}
+func (s *AgentService) UpdateThreadToolOutputLimit(id string, limit int) error {
+	return s.updateIdleThread(id, func(thread *Thread) error {
+		if err := thread.Agent.UpdateToolOutputLimit(limit); err != nil {
+			return fmt.Errorf("failed to update thread tool output limit: %w", err)
+		}
 
-	return s.persistThread(current)
+		thread.Info.ToolOutputLimit = limit
+		return nil
+	})
 }
 
 func (s *AgentService) UpdateThreadWorkDir(id string, workDir string) error {
-	s.mu.RLock()
-	thread, exists := s.agents[id]
-	s.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("thread not found: %s", id)
-	}
+	return s.updateIdleThread(id, func(thread *Thread) error {
+		if err := thread.Agent.UpdateWorkDir(workDir); err != nil {
+			return fmt.Errorf("failed to update thread work dir: %w", err)
+		}
 
-	if err := thread.Agent.UpdateWorkDir(workDir); err != nil {
-		return fmt.Errorf("failed to update thread work dir: %w", err)
-	}
+		thread.Info.WorkDir = thread.Agent.Config().WorkDir
+		return nil
+	})
+}
 
+// updateIdleThread changes the config of a thread that is not running. A run
+// reads the agent config without locking, so the config only changes between
+// runs, and holding the lock keeps a run from starting midway.
+func (s *AgentService) updateIdleThread(id string, update func(thread *Thread) error) error {
 	s.mu.Lock()
 	current, found := s.agents[id]
-	if found {
-		current.Info.WorkDir = workDir
-		current.Info.UpdatedAt = time.Now().UnixMilli()
+	if !found {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+	if current.running {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrThreadRunning, id)
 	}
-	s.mu.Unlock()
 
-	if !found {
-		return fmt.Errorf("thread not found: %s", id)
+	if err := update(current); err != nil {
+		s.mu.Unlock()
+		return err
 	}
+	current.Info.UpdatedAt = time.Now().UnixMilli()
+	s.mu.Unlock()
 
 	return s.persistThread(current)
 }
@@ -401,7 +723,7 @@ func (s *AgentService) UpdateThreadTitle(id string, title string) error {
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("thread not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 
 	s.mu.Lock()
@@ -413,7 +735,7 @@ func (s *AgentService) UpdateThreadTitle(id string, title string) error {
 	s.mu.Unlock()
 
 	if !found {
-		return fmt.Errorf("thread not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrThreadNotFound, id)
 	}
 
 	return s.persistThread(current)
@@ -432,11 +754,13 @@ func (s *AgentService) loadThreadsFromStorage(ctx context.Context) error {
 
 		info := stored.Info
 		config := models.AgentConfig{
-			ModelID: info.Model,
-			WorkDir: info.WorkDir,
+			ModelID:         info.Model,
+			WorkDir:         info.WorkDir,
+			ToolOutputLimit: info.ToolOutputLimit,
+			FallbackModels:  info.FallbackModels,
 		}
 
-		agent, err := NewAgentWithMessages(ctx, info.ID, config, stored.Messages, stored.MessageTimestamps, stored.Stats)
+		agent, err := NewAgentWithMessageTree(ctx, info.ID, config, stored.Nodes, stored.ActiveLeafID, stored.Stats, stored.Compaction)
 		if err != nil {
 			return err
 		}
@@ -450,22 +774,33 @@ func (s *AgentService) loadThreadsFromStorage(ctx context.Context) error {
 	return nil
 }
 
+// persistThread saves a thread unless it has been deleted. The read lock is
+// held while saving, so DeleteThread removes the files after any save that
+// started before it.
 func (s *AgentService) persistThread(thread *Thread) error {
 	if thread == nil || thread.Info == nil {
 		return fmt.Errorf("thread is nil")
 	}
 
-	messages, timestamps := thread.Agent.GetMessagesWithTimestamps()
-	if len(messages) != len(timestamps) {
-		return fmt.Errorf("thread messages and timestamps mismatch")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.agents[thread.Info.ID]; !exists {
+		return nil
 	}
 
+	return saveThread(thread)
+}
+
+func saveThread(thread *Thread) error {
+	nodes, activeLeafID := thread.Agent.MessageTree()
+
 	stats := thread.Agent.Stats()
 	if stats == nil {
 		return fmt.Errorf("thread stats is nil")
 	}
 
-	return storage.SaveThread(thread.Info, messages, timestamps, stats)
+	return storage.SaveThread(thread.Info, nodes, activeLeafID, stats, thread.Agent.Compaction())
 }
 
 func GenerateThreadTitle(ctx context.Context, messages []*models.ThreadMessage) (string, error) {
@@ -510,7 +845,7 @@ func GenerateThreadTitle(ctx context.Context, messages []*models.ThreadMessage)
 		},
 	}
 
-	model, err := getModel(ctx, getDefaultModelInfo().ID)
+	model, err := getModel(ctx, getDefaultModelInfo().ID, "")
 	if err != nil {
 		return "", fmt.Errorf("failed to generate thread title: %w", err)
 	}
@@ -11,6 +11,12 @@ type CodexPricing struct {
 	CacheReadInputCostPerToken float64
 }
 
+type ChatModelPricing struct {
+	InputCostPerToken          float64
+	OutputCostPerToken         float64
+	CacheReadInputCostPerToken float64
+}
+
 type ClaudePricing struct {
 	InputCostPerToken                            float64
 	OutputCostPerToken                           float64
@@ -51,6 +57,41 @@ var codexPricing = map[string]CodexPricing{
 	},
 }
 
+var chatModelPricing = map[string]ChatModelPricing{
+	"deepseek-chat": {
+		InputCostPerToken:          2.8e-7,
+		OutputCostPerToken:         4.2e-7,
+		CacheReadInputCostPerToken: 2.8e-8,
+	},
+	"deepseek-reasoner": {
+		InputCostPerToken:          2.8e-7,
+		OutputCostPerToken:         4.2e-7,
+		CacheReadInputCostPerToken: 2.8e-8,
+	},
+	"doubao-seed-1-8-251215": {
+		InputCostPerToken:          1.1e-7,
+		OutputCostPerToken:         1.1e-6,
+		CacheReadInputCostPerToken: 2.2e-8,
+	},
+	"kimi-k2-turbo-preview": {
+		InputCostPerToken:          1.15e-6,
+		OutputCostPerToken:         8e-6,
+		CacheReadInputCostPerToken: 1.5e-7,
+	},
+	"kimi-k2-thinking-turbo": {
+		InputCostPerToken:          1.15e-6,
+		OutputCostPerToken:         8e-6,
+		CacheReadInputCostPerToken: 1.5e-7,
+	},
+	"x-ai/grok-4.1-fast": {
+		InputCostPerToken:          2e-7,
+		OutputCostPerToken:         5e-7,
+		CacheReadInputCostPerToken: 5e-8,
+	},
+	"qwen/qwen3-coder:free":     {},
+	"xiaomi/mimo-v2-flash:free": {},
+}
+
 var claudePricing = map[string]ClaudePricing{
 	"claude-haiku-4-5-20251001": {
 		InputCostPerToken:              1e-6,
@@ -194,3 +235,16 @@ func GetClaudeCostUSD(model string, inputTokens, cacheReadInputTokens, cacheCrea
 
 	return &cost
 }
+
+func GetChatModelCostUSD(model string, inputTokens, cachedInputTokens, outputTokens int) *float64 {
+	pricing, ok := chatModelPricing[strings.TrimSpace(model)]
+	if !ok {
+		return nil
+	}
+	cached := min(max(0, cachedInputTokens), max(0, inputTokens))
+	nonCached := max(0, inputTokens-cached)
+	cost := float64(nonCached)*pricing.InputCostPerToken +
+		float64(cached)*pricing.CacheReadInputCostPerToken +
+		float64(max(0, outputTokens))*pricing.OutputCostPerToken
+	return &cost
+}
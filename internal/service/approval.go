@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/storage"
+	agentstool "github.com/zjregee/alter/internal/service/tools/agents"
+	filestool "github.com/zjregee/alter/internal/service/tools/files"
+)
+
+const deniedToolCallResult = "The user denied this tool call. Do not retry it unless the user asks you to."
+
+var defaultApprovalTools = []string{
+	agentstool.AgentsToolName,
+	filestool.WriteFileToolName,
+	filestool.EditFileToolName,
+	filestool.ApplyPatchToolName,
+}
+
+type pendingApproval struct {
+	toolName string
+	decision chan bool
+}
+
+func newDefaultToolApprovalSettings(workspacePath string) *models.ToolApprovalSettings {
+	return &models.ToolApprovalSettings{
+		WorkspacePath: workspacePath,
+		Mode:          models.ToolApprovalModeSelected,
+		Tools:         slices.Clone(defaultApprovalTools),
+		Decisions:     make(map[string]bool),
+	}
+}
+
+func getToolApprovalSettings(workspacePath string) (*models.ToolApprovalSettings, error) {
+	settings, err := storage.LoadToolApprovalSettings(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		return newDefaultToolApprovalSettings(workspacePath), nil
+	}
+	if settings.Decisions == nil {
+		settings.Decisions = make(map[string]bool)
+	}
+
+	return settings, nil
+}
+
+func updateToolApprovalSettings(workspacePath string, mode models.ToolApprovalMode, tools []string) error {
+	switch mode {
+	case models.ToolApprovalModeNever, models.ToolApprovalModeSelected, models.ToolApprovalModeAlways:
+	default:
+		return fmt.Errorf("unsupported tool approval mode: %s", mode)
+	}
+
+	settings, err := getToolApprovalSettings(workspacePath)
+	if err != nil {
+		return err
+	}
+
+	settings.Mode = mode
+	settings.Tools = tools
+	return storage.SaveToolApprovalSettings(settings)
+}
+
+func rememberToolApprovalDecision(workspacePath string, toolName string, allow bool) error {
+	settings, err := getToolApprovalSettings(workspacePath)
+	if err != nil {
+		return err
+	}
+
+	settings.Decisions[toolName] = allow
+	return storage.SaveToolApprovalSettings(settings)
+}
+
+func clearToolApprovalDecisions(workspacePath string) error {
+	settings, err := getToolApprovalSettings(workspacePath)
+	if err != nil {
+		return err
+	}
+
+	settings.Decisions = make(map[string]bool)
+	return storage.SaveToolApprovalSettings(settings)
+}
+
+func requiresToolApproval(settings *models.ToolApprovalSettings, toolName string) bool {
+	switch settings.Mode {
+	case models.ToolApprovalModeAlways:
+		return true
+	case models.ToolApprovalModeSelected:
+		return slices.Contains(settings.Tools, toolName)
+	default:
+		return false
+	}
+}
+
+// awaitToolApproval reports whether the call may run, and whether the user
+// approved a bash command the policy asks about. Remembered decisions are per
+// tool, so they never answer a policy question about one particular command.
+func (a *Agent) awaitToolApproval(ctx context.Context, toolID int, tc schema.ToolCall, msgChan chan<- models.AgentMessage) (bool, bool, error) {
+	settings, err := getToolApprovalSettings(a.config.WorkDir)
+	if err != nil {
+		return false, false, err
+	}
+
+	required := requiresToolApproval(settings, tc.Function.Name)
+	policyAsk := false
+	reason := ""
+	if decision := a.checkBashCall(tc); decision != nil && decision.Action == models.BashPolicyAsk {
+		required = true
+		policyAsk = true
+		reason = decision.Reason
+	}
+	if !required {
+		return true, false, nil
+	}
+	if allow, ok := settings.Decisions[tc.Function.Name]; ok && !policyAsk {
+		return allow, false, nil
+	}
+
+	pending := &pendingApproval{
+		toolName: tc.Function.Name,
+		decision: make(chan bool, 1),
+	}
+
+	a.approvalsMu.Lock()
+	a.pendingApprovals[tc.ID] = pending
+	a.approvalsMu.Unlock()
+
+	defer func() {
+		a.approvalsMu.Lock()
+		delete(a.pendingApprovals, tc.ID)
+		a.approvalsMu.Unlock()
+	}()
+
+	msgChan <- models.AgentToolApprovalRequest{
+		ID:     toolID,
+		CallID: tc.ID,
+		Name:   tc.Function.Name,
+		Args:   tc.Function.Arguments,
+		Reason: reason,
+		Diffs:  a.previewToolCall(ctx, tc),
+	}
+
+	select {
+	case allow := <-pending.decision:
+		return allow, allow && policyAsk, nil
+	case <-ctx.Done():
+		return false, false, ctx.Err()
+	}
+}
+
+func (a *Agent) RespondToolApproval(callID string, allow bool) (string, error) {
+	a.approvalsMu.Lock()
+	pending, ok := a.pendingApprovals[callID]
+	a.approvalsMu.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrToolApprovalNotFound, callID)
+	}
+
+	select {
+	case pending.decision <- allow:
+	default:
+		return "", fmt.Errorf("%w: %s", ErrToolApprovalAnswered, callID)
+	}
+
+	return pending.toolName, nil
+}
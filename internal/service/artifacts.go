@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/service/storage"
+	"github.com/zjregee/alter/internal/service/tools/artifacts"
+)
+
+const artifactPreviewBytes = 2000
+
+func (a *Agent) spillToolOutput(tc schema.ToolCall, result string) string {
+	if len(result) <= a.config.ToolOutputLimit || tc.Function.Name == artifacts.ReadArtifactToolName {
+		return result
+	}
+
+	artifactID, err := storage.SaveArtifact(a.id, []byte(result))
+	if err != nil {
+		fmt.Printf("Failed to save tool %s output of agent %s as artifact: %v\n", tc.Function.Name, a.id, err)
+		return result
+	}
+
+	return buildArtifactPreview(artifactID, result)
+}
+
+// buildArtifactPreview shows the head and tail of content. Each is at most half
+// of it, so a preview never repeats bytes even under a small output limit.
+func buildArtifactPreview(artifactID string, content string) string {
+	previewBytes := min(artifactPreviewBytes, len(content)/2)
+	head := truncateRunesFromEnd(content[:previewBytes])
+	tail := truncateRunesFromStart(content[len(content)-previewBytes:])
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Tool output too large: %d bytes, %d lines. Saved as artifact %s.]\n", len(content), strings.Count(content, "\n")+1, artifactID)
+	fmt.Fprintf(&b, "[Use the %s tool with this artifact_id to page through the full output.]\n\n", artifacts.ReadArtifactToolName)
+	fmt.Fprintf(&b, "--- First %d bytes ---\n", len(head))
+	b.WriteString(head)
+	fmt.Fprintf(&b, "\n...\n--- Last %d bytes ---\n", len(tail))
+	b.WriteString(tail)
+
+	return b.String()
+}
+
+func truncateRunesFromEnd(s string) string {
+	for len(s) > 0 {
+		r, size := utf8.DecodeLastRuneInString(s)
+		if r != utf8.RuneError || size != 1 {
+			break
+		}
+		s = s[:len(s)-1]
+	}
+
+	return s
+}
+
+func truncateRunesFromStart(s string) string {
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r != utf8.RuneError || size != 1 {
+			break
+		}
+		s = s[1:]
+	}
+
+	return s
+}
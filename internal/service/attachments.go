@@ -0,0 +1,288 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+const (
+	attachmentExtraKey    = "alter_attachment"
+	maxAttachmentSize     = 20 * 1024 * 1024
+	imageAttachmentTokens = 1_000
+	defaultAttachmentName = "image"
+)
+
+var supportedImageMIMETypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+}
+
+func (s *AgentService) PrepareAttachments(id string, inputs []*models.AttachmentInput) ([]*models.Attachment, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	thread, exists := s.agents[id]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	modelID := thread.Agent.Config().ModelID
+	if !supportsVision(modelID) {
+		return nil, fmt.Errorf("model %s does not support image input, switch to a vision model to send images", modelID)
+	}
+
+	attachments := make([]*models.Attachment, 0, len(inputs))
+	for i, input := range inputs {
+		if input == nil {
+			continue
+		}
+
+		name, mimeType, data, err := readAttachmentInput(input, i)
+		if err != nil {
+			return nil, err
+		}
+
+		attachment := &models.Attachment{
+			ID:       GenerateAttachmentID(),
+			Name:     name,
+			MIMEType: mimeType,
+			Size:     len(data),
+		}
+		if err := storage.SaveAttachment(id, attachment, data); err != nil {
+			return nil, fmt.Errorf("failed to save attachment %s: %w", name, err)
+		}
+
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+func (s *AgentService) GetAttachmentData(id string, attachmentID string) (string, error) {
+	s.mu.RLock()
+	_, exists := s.agents[id]
+	s.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	record, err := storage.LoadAttachment(id, attachmentID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("data:%s;base64,%s", record.Info.MIMEType, base64.StdEncoding.EncodeToString(record.Data)), nil
+}
+
+func readAttachmentInput(input *models.AttachmentInput, index int) (string, string, []byte, error) {
+	name := strings.TrimSpace(input.Name)
+	data := input.Data
+
+	if len(data) == 0 {
+		path := strings.TrimSpace(input.Path)
+		if path == "" {
+			return "", "", nil, fmt.Errorf("attachment %d has neither data nor path", index)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to read attachment %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return "", "", nil, fmt.Errorf("attachment is a directory: %s", path)
+		}
+		if info.Size() > maxAttachmentSize {
+			return "", "", nil, fmt.Errorf("attachment %s is too large: %d bytes (limit %d)", path, info.Size(), maxAttachmentSize)
+		}
+
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to read attachment %s: %w", path, err)
+		}
+		if name == "" {
+			name = filepath.Base(path)
+		}
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%s-%d", defaultAttachmentName, index+1)
+	}
+	if len(data) > maxAttachmentSize {
+		return "", "", nil, fmt.Errorf("attachment %s is too large: %d bytes (limit %d)", name, len(data), maxAttachmentSize)
+	}
+
+	mimeType := strings.ToLower(strings.TrimSpace(input.MIMEType))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if !slices.Contains(supportedImageMIMETypes, mimeType) {
+		return "", "", nil, fmt.Errorf("attachment %s has unsupported type %s, only PNG, JPEG, GIF and WebP images are supported", name, mimeType)
+	}
+
+	return name, mimeType, data, nil
+}
+
+func buildUserMessage(userInput string, attachments []*models.Attachment) *schema.Message {
+	msg := &schema.Message{
+		Role:    schema.User,
+		Content: userInput,
+	}
+	if len(attachments) == 0 {
+		return msg
+	}
+
+	parts := make([]schema.MessageInputPart, 0, len(attachments)+1)
+	if strings.TrimSpace(userInput) != "" {
+		parts = append(parts, schema.MessageInputPart{
+			Type: schema.ChatMessagePartTypeText,
+			Text: userInput,
+		})
+	}
+	for _, attachment := range attachments {
+		parts = append(parts, schema.MessageInputPart{
+			Type: schema.ChatMessagePartTypeImageURL,
+			Image: &schema.MessageInputImage{
+				MessagePartCommon: schema.MessagePartCommon{
+					MIMEType: attachment.MIMEType,
+				},
+			},
+			Extra: map[string]any{
+				attachmentExtraKey: attachment,
+			},
+		})
+	}
+	msg.UserInputMultiContent = parts
+
+	return msg
+}
+
+func messageAttachments(msg *schema.Message) []*models.Attachment {
+	var attachments []*models.Attachment
+	for _, part := range msg.UserInputMultiContent {
+		if attachment := partAttachment(part); attachment != nil {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments
+}
+
+func partAttachment(part schema.MessageInputPart) *models.Attachment {
+	if part.Type != schema.ChatMessagePartTypeImageURL {
+		return nil
+	}
+
+	switch value := part.Extra[attachmentExtraKey].(type) {
+	case *models.Attachment:
+		return value
+	case map[string]any:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		var attachment models.Attachment
+		if err := json.Unmarshal(data, &attachment); err != nil {
+			return nil
+		}
+		return &attachment
+	}
+
+	return nil
+}
+
+func (a *Agent) currentTurnHasAttachments() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for i := len(a.messages) - 1; i > 0; i -= 1 {
+		msg := a.messages[i]
+		if msg.Role == schema.User && !isInjectedMessage(msg) {
+			return len(messageAttachments(msg)) > 0
+		}
+	}
+
+	return false
+}
+
+func (a *Agent) resolveAttachments(messages []*schema.Message, modelID string) []*schema.Message {
+	vision := supportsVision(modelID)
+
+	result := make([]*schema.Message, len(messages))
+	for i, msg := range messages {
+		if msg.Role != schema.User || len(msg.UserInputMultiContent) == 0 {
+			result[i] = msg
+			continue
+		}
+
+		resolved := *msg
+		resolved.UserInputMultiContent = make([]schema.MessageInputPart, 0, len(msg.UserInputMultiContent))
+		for _, part := range msg.UserInputMultiContent {
+			attachment := partAttachment(part)
+			if attachment == nil {
+				resolved.UserInputMultiContent = append(resolved.UserInputMultiContent, part)
+				continue
+			}
+
+			var record *storage.AttachmentRecord
+			var err error
+			if vision {
+				record, err = storage.LoadAttachment(a.id, attachment.ID)
+			}
+			if !vision || err != nil {
+				resolved.UserInputMultiContent = append(resolved.UserInputMultiContent, schema.MessageInputPart{
+					Type: schema.ChatMessagePartTypeText,
+					Text: fmt.Sprintf("[Image attachment %s omitted]", attachment.Name),
+				})
+				continue
+			}
+
+			data := base64.StdEncoding.EncodeToString(record.Data)
+			resolved.UserInputMultiContent = append(resolved.UserInputMultiContent, schema.MessageInputPart{
+				Type: schema.ChatMessagePartTypeImageURL,
+				Image: &schema.MessageInputImage{
+					MessagePartCommon: schema.MessagePartCommon{
+						Base64Data: &data,
+						MIMEType:   record.Info.MIMEType,
+					},
+				},
+			})
+		}
+
+		if !vision {
+			resolved.Content = flattenTextParts(resolved.UserInputMultiContent)
+			resolved.UserInputMultiContent = nil
+		}
+		result[i] = &resolved
+	}
+
+	return result
+}
+
+func flattenTextParts(parts []schema.MessageInputPart) string {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == schema.ChatMessagePartTypeText && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/storage"
+	bashtool "github.com/zjregee/alter/internal/service/tools/bash"
+)
+
+func getBashPolicy(workspacePath string) (*models.BashPolicy, error) {
+	policy, err := storage.LoadBashPolicy(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == nil {
+		return bashtool.DefaultPolicy(workspacePath), nil
+	}
+
+	return policy, nil
+}
+
+func updateBashPolicy(workspacePath string, defaultAction models.BashPolicyAction, rules []*models.BashCommandRule, sandbox bool) error {
+	if !isBashPolicyAction(defaultAction) {
+		return fmt.Errorf("unsupported bash policy action: %s", defaultAction)
+	}
+
+	normalized := make([]*models.BashCommandRule, 0, len(rules))
+	for i, rule := range rules {
+		if rule == nil {
+			continue
+		}
+		command := strings.TrimSpace(rule.Command)
+		if command == "" {
+			return fmt.Errorf("bash rule %d command is required", i)
+		}
+		if !isBashPolicyAction(rule.Action) {
+			return fmt.Errorf("bash rule %d has unsupported action: %s", i, rule.Action)
+		}
+
+		var args []string
+		for _, arg := range rule.Args {
+			if arg = strings.TrimSpace(arg); arg != "" {
+				args = append(args, arg)
+			}
+		}
+
+		normalized = append(normalized, &models.BashCommandRule{
+			Command: command,
+			Args:    args,
+			Action:  rule.Action,
+		})
+	}
+
+	return storage.SaveBashPolicy(&models.BashPolicy{
+		WorkspacePath: workspacePath,
+		DefaultAction: defaultAction,
+		Rules:         normalized,
+		Sandbox:       sandbox,
+	})
+}
+
+func isBashPolicyAction(action models.BashPolicyAction) bool {
+	switch action {
+	case models.BashPolicyAllow, models.BashPolicyAsk, models.BashPolicyDeny:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkBashCall evaluates a bash tool call against the workspace policy. Calls
+// with malformed arguments are left to the tool, which rejects them, and a
+// policy that cannot be loaded makes every command need approval.
+func (a *Agent) checkBashCall(tc schema.ToolCall) *bashtool.Decision {
+	if tc.Function.Name != bashtool.BashToolName {
+		return nil
+	}
+
+	var params bashtool.BashParams
+	if err := json.Unmarshal([]byte(tc.Function.Arguments), &params); err != nil {
+		return nil
+	}
+
+	policy, err := getBashPolicy(a.config.WorkDir)
+	if err != nil {
+		fmt.Printf("Failed to load bash policy: %v\n", err)
+		return &bashtool.Decision{
+			Action: models.BashPolicyAsk,
+			Reason: fmt.Sprintf("the bash policy could not be loaded: %v", err),
+		}
+	}
+
+	decision, err := bashtool.CheckCommand(policy, strings.TrimSpace(params.Command))
+	if err != nil {
+		return &bashtool.Decision{Action: models.BashPolicyDeny, Reason: err.Error()}
+	}
+
+	return decision
+}
+
+func (s *AgentService) GetBashPolicy(workspacePath string) (*models.BashPolicy, error) {
+	return getBashPolicy(workspacePath)
+}
+
+func (s *AgentService) UpdateBashPolicy(workspacePath string, defaultAction models.BashPolicyAction, rules []*models.BashCommandRule, sandbox bool) error {
+	return updateBashPolicy(workspacePath, defaultAction, rules, sandbox)
+}
+
+func (s *AgentService) ResetBashPolicy(workspacePath string) error {
+	return storage.DeleteBashPolicy(workspacePath)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+const branchPreviewLength = 80
+
+func (a *Agent) setActivePathLocked(leafID string) error {
+	path := make([]*models.MessageNode, 0)
+	visited := make(map[string]bool)
+	for id := leafID; id != ""; {
+		node, ok := a.nodes[id]
+		if !ok {
+			return fmt.Errorf("message not found: %s", id)
+		}
+		if visited[id] {
+			return fmt.Errorf("message tree has a cycle at %s", id)
+		}
+		visited[id] = true
+		path = append(path, node)
+		id = node.ParentID
+	}
+
+	if len(path) == 0 {
+		return fmt.Errorf("message path is empty")
+	}
+	slices.Reverse(path)
+
+	if path[0].Message.Role != schema.System {
+		return fmt.Errorf("message path does not start with a system message")
+	}
+
+	a.messages = make([]*schema.Message, 0, len(path))
+	a.messageTimestamps = make([]int64, 0, len(path))
+	a.messageIDs = make([]string, 0, len(path))
+	for _, node := range path {
+		a.messages = append(a.messages, node.Message)
+		a.messageTimestamps = append(a.messageTimestamps, node.Timestamp)
+		a.messageIDs = append(a.messageIDs, node.ID)
+	}
+
+	return nil
+}
+
+func (a *Agent) resolveMessageIndexLocked(index int) (int, error) {
+	nonSystemIndex := -1
+	for i, msg := range a.messages {
+		if msg.Role == schema.System {
+			continue
+		}
+		nonSystemIndex += 1
+		if nonSystemIndex == index {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("invalid message index: %d", index)
+}
+
+func (a *Agent) RewindBefore(index int) error {
+	return a.rewind(index, false)
+}
+
+func (a *Agent) RewindThrough(index int) error {
+	return a.rewind(index, true)
+}
+
+func (a *Agent) rewind(index int, inclusive bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	actualIndex, err := a.resolveMessageIndexLocked(index)
+	if err != nil {
+		return err
+	}
+
+	keep := actualIndex
+	if inclusive {
+		keep += 1
+	}
+
+	a.messages = a.messages[:keep:keep]
+	a.messageTimestamps = a.messageTimestamps[:keep:keep]
+	a.messageIDs = a.messageIDs[:keep:keep]
+	a.clampCompaction()
+
+	return nil
+}
+
+func (a *Agent) addNodeLocked(node *models.MessageNode) {
+	a.nodes[node.ID] = node
+
+	siblings := a.children[node.ParentID]
+	i, _ := slices.BinarySearchFunc(siblings, node, compareMessageNodes)
+	a.children[node.ParentID] = slices.Insert(siblings, i, node)
+}
+
+// childrenLocked returns the children of a message in creation order. The
+// slice is shared with the index and must not be modified.
+func (a *Agent) childrenLocked(parentID string) []*models.MessageNode {
+	return a.children[parentID]
+}
+
+func (a *Agent) ListBranches(index int) ([]*models.MessageBranch, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	actualIndex, err := a.resolveMessageIndexLocked(index)
+	if err != nil {
+		return nil, err
+	}
+
+	current := a.nodes[a.messageIDs[actualIndex]]
+	siblings := a.childrenLocked(current.ParentID)
+
+	branches := make([]*models.MessageBranch, 0, len(siblings))
+	for _, sibling := range siblings {
+		branches = append(branches, &models.MessageBranch{
+			ID:        sibling.ID,
+			Preview:   branchPreview(sibling.Message),
+			Timestamp: sibling.Timestamp,
+			Active:    sibling.ID == current.ID,
+		})
+	}
+
+	return branches, nil
+}
+
+func (a *Agent) SwitchBranch(messageID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancelFunc != nil {
+		return ErrThreadRunning
+	}
+
+	target, ok := a.nodes[messageID]
+	if !ok {
+		return fmt.Errorf("message not found: %s", messageID)
+	}
+
+	leaf := target
+	for {
+		children := a.childrenLocked(leaf.ID)
+		if len(children) == 0 {
+			break
+		}
+		leaf = children[len(children)-1]
+	}
+
+	previousIDs := a.messageIDs
+	if err := a.setActivePathLocked(leaf.ID); err != nil {
+		return err
+	}
+
+	common := 0
+	for common < len(previousIDs) && common < len(a.messageIDs) && previousIDs[common] == a.messageIDs[common] {
+		common += 1
+	}
+
+	if a.compaction != nil {
+		if a.compaction.SummarizedUntil > common {
+			a.compaction = nil
+		} else {
+			a.compaction.ElidedToolResultsUntil = min(a.compaction.ElidedToolResultsUntil, common)
+		}
+	}
+
+	return nil
+}
+
+func (a *Agent) MessageTree() ([]*models.MessageNode, string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	nodes := make([]*models.MessageNode, 0, len(a.nodes))
+	for _, node := range a.nodes {
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, compareMessageNodes)
+
+	return nodes, a.messageIDs[len(a.messageIDs)-1]
+}
+
+func (a *Agent) GetActivePath() []*models.MessageNode {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	path := make([]*models.MessageNode, 0, len(a.messageIDs))
+	for _, id := range a.messageIDs {
+		path = append(path, a.nodes[id])
+	}
+
+	return path
+}
+
+func (a *Agent) BranchPosition(messageID string) (int, int) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	node, ok := a.nodes[messageID]
+	if !ok {
+		return 0, 0
+	}
+
+	siblings := a.childrenLocked(node.ParentID)
+	for i, sibling := range siblings {
+		if sibling.ID == messageID {
+			return i, len(siblings)
+		}
+	}
+
+	return 0, len(siblings)
+}
+
+func compareMessageNodes(x, y *models.MessageNode) int {
+	return cmp.Or(cmp.Compare(x.Timestamp, y.Timestamp), strings.Compare(x.ID, y.ID))
+}
+
+func branchPreview(msg *schema.Message) string {
+	content := strings.TrimSpace(displayContent(msg))
+	runes := []rune(content)
+	if len(runes) <= branchPreviewLength {
+		return content
+	}
+
+	return string(runes[:branchPreviewLength]) + "..."
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+var dailyUsageMu sync.Mutex
+
+func getBudgetSettings() (*models.BudgetSettings, error) {
+	settings, err := storage.LoadBudgetSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		return &models.BudgetSettings{}, nil
+	}
+
+	return settings, nil
+}
+
+func updateBudgetSettings(settings *models.BudgetSettings) error {
+	if settings == nil {
+		return fmt.Errorf("budget settings are required")
+	}
+
+	for scope, limit := range map[models.BudgetScope]models.BudgetLimit{
+		models.BudgetScopeRun:    settings.Run,
+		models.BudgetScopeThread: settings.Thread,
+		models.BudgetScopeDay:    settings.Day,
+	} {
+		if limit.MaxTokens < 0 || limit.MaxCostUSD < 0 {
+			return fmt.Errorf("%s budget must not be negative", scope)
+		}
+	}
+
+	return storage.SaveBudgetSettings(settings)
+}
+
+func usageDay(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+func getDailyUsage(day string) (*models.AgentUsage, error) {
+	usage, err := storage.LoadDailyUsage(day)
+	if err != nil {
+		return nil, err
+	}
+
+	if usage == nil {
+		return &models.AgentUsage{}, nil
+	}
+
+	return usage, nil
+}
+
+func addDailyUsage(turn *models.AgentTurnUsage) error {
+	dailyUsageMu.Lock()
+	defer dailyUsageMu.Unlock()
+
+	day := usageDay(time.UnixMilli(turn.Timestamp))
+	usage, err := getDailyUsage(day)
+	if err != nil {
+		return err
+	}
+
+	addUsage(usage, turn)
+	return storage.SaveDailyUsage(day, usage)
+}
+
+// checkBudgets runs before every turn. A continued run skips the thread and
+// daily budgets that stopped it, but is still bound by the per-run budget.
+func (a *Agent) checkBudgets() (*models.AgentBudgetExceeded, error) {
+	settings, err := getBudgetSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	runUsage := a.runUsage
+	threadUsage := *a.stats.Usage
+	waived := a.budgetWaived
+	a.mu.RUnlock()
+
+	if exceeded := exceededBudget(models.BudgetScopeRun, settings.Run, &runUsage); exceeded != nil {
+		return exceeded, nil
+	}
+	if waived {
+		return nil, nil
+	}
+	if exceeded := exceededBudget(models.BudgetScopeThread, settings.Thread, &threadUsage); exceeded != nil {
+		return exceeded, nil
+	}
+
+	if settings.Day.MaxTokens <= 0 && settings.Day.MaxCostUSD <= 0 {
+		return nil, nil
+	}
+	dailyUsage, err := getDailyUsage(usageDay(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	return exceededBudget(models.BudgetScopeDay, settings.Day, dailyUsage), nil
+}
+
+func exceededBudget(scope models.BudgetScope, limit models.BudgetLimit, usage *models.AgentUsage) *models.AgentBudgetExceeded {
+	if limit.MaxTokens > 0 && usage.TotalTokens >= limit.MaxTokens {
+		return &models.AgentBudgetExceeded{
+			Scope:  scope,
+			Metric: models.BudgetMetricTokens,
+			Limit:  float64(limit.MaxTokens),
+			Used:   float64(usage.TotalTokens),
+		}
+	}
+	if limit.MaxCostUSD > 0 && usage.CostUSD >= limit.MaxCostUSD {
+		return &models.AgentBudgetExceeded{
+			Scope:  scope,
+			Metric: models.BudgetMetricCostUSD,
+			Limit:  limit.MaxCostUSD,
+			Used:   usage.CostUSD,
+		}
+	}
+
+	return nil
+}
+
+func (s *AgentService) GetBudgetSettings() (*models.BudgetSettings, error) {
+	return getBudgetSettings()
+}
+
+func (s *AgentService) UpdateBudgetSettings(settings *models.BudgetSettings) error {
+	return updateBudgetSettings(settings)
+}
+
+func (s *AgentService) GetDailyUsage(day string) (*models.AgentUsage, error) {
+	if day == "" {
+		day = usageDay(time.Now())
+	}
+	if _, err := time.Parse(time.DateOnly, day); err != nil {
+		return nil, fmt.Errorf("invalid usage day %s: %w", day, err)
+	}
+
+	return getDailyUsage(day)
+}
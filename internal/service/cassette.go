@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+
+	"github.com/zjregee/alter/internal/service/cassette"
+)
+
+const (
+	cassetteModeEnv = "ALTER_CASSETTE_MODE"
+	cassettePathEnv = "ALTER_CASSETTE"
+)
+
+const (
+	CassetteModeRecord = "record"
+	CassetteModeReplay = "replay"
+)
+
+var (
+	activeCassette     *cassette.Cassette
+	activeCassetteMode string
+	activeCassetteMu   sync.RWMutex
+)
+
+func init() {
+	mode := os.Getenv(cassetteModeEnv)
+	if mode == "" {
+		return
+	}
+
+	if _, err := UseCassette(mode, os.Getenv(cassettePathEnv)); err != nil {
+		fmt.Printf("Failed to enable cassette: %v\n", err)
+	}
+}
+
+func UseCassette(mode string, path string) (*cassette.Cassette, error) {
+	activeCassetteMu.Lock()
+	defer activeCassetteMu.Unlock()
+
+	if mode == "" {
+		activeCassette = nil
+		activeCassetteMode = ""
+		return nil, nil
+	}
+	if path == "" {
+		return nil, fmt.Errorf("cassette path is required")
+	}
+
+	var c *cassette.Cassette
+	switch mode {
+	case CassetteModeRecord:
+		c = cassette.New(path)
+	case CassetteModeReplay:
+		loaded, err := cassette.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		c = loaded
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %s", mode)
+	}
+
+	activeCassette = c
+	activeCassetteMode = mode
+	return c, nil
+}
+
+func cassetteReplayer(modelID string, workDir string) model.ToolCallingChatModel {
+	activeCassetteMu.RLock()
+	defer activeCassetteMu.RUnlock()
+
+	if activeCassetteMode != CassetteModeReplay {
+		return nil
+	}
+
+	return cassette.NewReplayer(activeCassette, modelID, workDir)
+}
+
+func recordWithCassette(modelID string, workDir string, chatModel model.ToolCallingChatModel) model.ToolCallingChatModel {
+	activeCassetteMu.RLock()
+	defer activeCassetteMu.RUnlock()
+
+	if activeCassetteMode != CassetteModeRecord {
+		return chatModel
+	}
+
+	return cassette.NewRecorder(activeCassette, modelID, workDir, chatModel)
+}
@@ -0,0 +1,117 @@
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+const cassetteVersion = 1
+
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+
+	mu   sync.Mutex
+	path string
+	next int
+}
+
+type Interaction struct {
+	ModelID  string            `json:"model_id"`
+	Messages []*schema.Message `json:"messages"`
+	Tools    []*Tool           `json:"tools,omitempty"`
+	Stream   bool              `json:"stream"`
+	Response *schema.Message   `json:"response,omitempty"`
+	Chunks   []*schema.Message `json:"chunks,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+func New(path string) *Cassette {
+	return &Cassette{
+		Version: cassetteVersion,
+		path:    path,
+	}
+}
+
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", c.Version, path)
+	}
+	c.path = path
+
+	return &c, nil
+}
+
+func (c *Cassette) Path() string {
+	return c.path
+}
+
+func (c *Cassette) append(interaction *Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Interactions = append(c.Interactions, interaction)
+	return c.saveLocked()
+}
+
+func (c *Cassette) saveLocked() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cassette %s: %w", c.path, err)
+	}
+
+	return os.Rename(tmpPath, c.path)
+}
+
+func recordTools(tools []*schema.ToolInfo) ([]*Tool, error) {
+	recorded := make([]*Tool, 0, len(tools))
+	for _, info := range tools {
+		tool := &Tool{
+			Name:        info.Name,
+			Description: info.Desc,
+		}
+		if info.ParamsOneOf != nil {
+			params, err := info.ParamsOneOf.ToJSONSchema()
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert tool %s parameters: %w", info.Name, err)
+			}
+			data, err := json.Marshal(params)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal tool %s parameters: %w", info.Name, err)
+			}
+			tool.Parameters = data
+		}
+		recorded = append(recorded, tool)
+	}
+
+	return recorded, nil
+}
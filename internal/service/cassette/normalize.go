@@ -0,0 +1,99 @@
+package cassette
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	workDirPlaceholder = "[WORK_DIR]"
+	timePlaceholder    = "[TIME]"
+)
+
+var timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+
+// normalizer replaces the parts of a request that change between runs, the
+// work dir and the time in the system prompt, with placeholders, so a cassette
+// recorded in one work dir replays in another and every request can still be
+// compared in full.
+type normalizer struct {
+	workDir  string
+	workDirs []string
+}
+
+func newNormalizer(workDir string) *normalizer {
+	n := &normalizer{workDir: workDir}
+	if workDir == "" {
+		return n
+	}
+
+	n.workDirs = []string{workDir}
+	if resolved, err := filepath.EvalSymlinks(workDir); err == nil && resolved != workDir {
+		n.workDirs = append(n.workDirs, resolved)
+	}
+	// A resolved path can contain the unresolved one, like /private/tmp/x
+	// and /tmp/x, so the longer one is replaced first.
+	slices.SortFunc(n.workDirs, func(a, b string) int { return len(b) - len(a) })
+
+	return n
+}
+
+func (n *normalizer) text(value string) string {
+	for _, dir := range n.workDirs {
+		value = strings.ReplaceAll(value, dir, workDirPlaceholder)
+	}
+
+	return value
+}
+
+func (n *normalizer) messages(messages []*schema.Message) []*schema.Message {
+	normalized := make([]*schema.Message, 0, len(messages))
+	for _, msg := range messages {
+		normalized = append(normalized, n.message(msg))
+	}
+
+	return normalized
+}
+
+func (n *normalizer) message(msg *schema.Message) *schema.Message {
+	if msg == nil {
+		return nil
+	}
+
+	normalized := mapMessage(msg, n.text)
+	if msg.Role == schema.System {
+		normalized.Content = timestampPattern.ReplaceAllString(normalized.Content, timePlaceholder)
+	}
+
+	return normalized
+}
+
+// expand puts the work dir of the replay back into a recorded response.
+func (n *normalizer) expand(msg *schema.Message) *schema.Message {
+	if msg == nil || n.workDir == "" {
+		return msg
+	}
+
+	return mapMessage(msg, func(value string) string {
+		return strings.ReplaceAll(value, workDirPlaceholder, n.workDir)
+	})
+}
+
+func mapMessage(msg *schema.Message, fn func(string) string) *schema.Message {
+	mapped := *msg
+	mapped.Content = fn(msg.Content)
+	mapped.ReasoningContent = fn(msg.ReasoningContent)
+	if len(msg.ToolCalls) > 0 {
+		mapped.ToolCalls = make([]schema.ToolCall, len(msg.ToolCalls))
+		for i, tc := range msg.ToolCalls {
+			tc.Function.Arguments = fn(tc.Function.Arguments)
+			mapped.ToolCalls[i] = tc
+		}
+	}
+
+	return &mapped
+}
@@ -0,0 +1,120 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+type Recorder struct {
+	cassette   *Cassette
+	modelID    string
+	normalizer *normalizer
+	inner      model.ToolCallingChatModel
+	tools      []*schema.ToolInfo
+}
+
+func NewRecorder(c *Cassette, modelID string, workDir string, inner model.ToolCallingChatModel) *Recorder {
+	return &Recorder{
+		cassette:   c,
+		modelID:    modelID,
+		normalizer: newNormalizer(workDir),
+		inner:      inner,
+	}
+}
+
+func (r *Recorder) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	response, err := r.inner.Generate(ctx, input, opts...)
+
+	interaction, recordErr := r.newInteraction(input, false)
+	if recordErr != nil {
+		return nil, recordErr
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		interaction.Response = r.normalizer.message(response)
+	}
+
+	if recordErr := r.cassette.append(interaction); recordErr != nil {
+		return nil, recordErr
+	}
+
+	return response, err
+}
+
+func (r *Recorder) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	interaction, err := r.newInteraction(input, true)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := r.inner.Stream(ctx, input, opts...)
+	if err != nil {
+		interaction.Error = err.Error()
+		if recordErr := r.cassette.append(interaction); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+	defer stream.Close()
+
+	chunks := make([]*schema.Message, 0)
+	for {
+		chunk, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		if recvErr != nil {
+			err = recvErr
+			break
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	interaction.Chunks = r.normalizer.messages(chunks)
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+	if recordErr := r.cassette.append(interaction); recordErr != nil {
+		return nil, recordErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+func (r *Recorder) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	inner, err := r.inner.WithTools(tools)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		cassette:   r.cassette,
+		modelID:    r.modelID,
+		normalizer: r.normalizer,
+		inner:      inner,
+		tools:      tools,
+	}, nil
+}
+
+func (r *Recorder) newInteraction(input []*schema.Message, stream bool) (*Interaction, error) {
+	tools, err := recordTools(r.tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record request: %w", err)
+	}
+
+	return &Interaction{
+		ModelID:  r.modelID,
+		Messages: r.normalizer.messages(input),
+		Tools:    tools,
+		Stream:   stream,
+	}, nil
+}
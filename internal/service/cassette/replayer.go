@@ -0,0 +1,180 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+type Replayer struct {
+	cassette   *Cassette
+	modelID    string
+	normalizer *normalizer
+	tools      []*schema.ToolInfo
+}
+
+func NewReplayer(c *Cassette, modelID string, workDir string) *Replayer {
+	return &Replayer{
+		cassette:   c,
+		modelID:    modelID,
+		normalizer: newNormalizer(workDir),
+	}
+}
+
+func (r *Replayer) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	interaction, err := r.match(ctx, input, false)
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+
+	return r.normalizer.expand(interaction.Response), nil
+}
+
+func (r *Replayer) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	interaction, err := r.match(ctx, input, true)
+	if err != nil {
+		return nil, err
+	}
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+
+	chunks := make([]*schema.Message, 0, len(interaction.Chunks))
+	for _, chunk := range interaction.Chunks {
+		chunks = append(chunks, r.normalizer.expand(chunk))
+	}
+
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+func (r *Replayer) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return &Replayer{
+		cassette:   r.cassette,
+		modelID:    r.modelID,
+		normalizer: r.normalizer,
+		tools:      tools,
+	}, nil
+}
+
+func (c *Cassette) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.Interactions) - c.next
+}
+
+func (r *Replayer) match(ctx context.Context, input []*schema.Message, stream bool) (*Interaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c := r.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next >= len(c.Interactions) {
+		return nil, fmt.Errorf("cassette mismatch: no recorded interaction left after %d", len(c.Interactions))
+	}
+
+	index := c.next
+	interaction := c.Interactions[index]
+
+	if interaction.ModelID != r.modelID {
+		return nil, fmt.Errorf("cassette mismatch at interaction %d: model %s, recorded %s", index, r.modelID, interaction.ModelID)
+	}
+	if interaction.Stream != stream {
+		return nil, fmt.Errorf("cassette mismatch at interaction %d: stream %t, recorded %t", index, stream, interaction.Stream)
+	}
+	if err := compareMessages(r.normalizer.messages(input), interaction.Messages); err != nil {
+		return nil, fmt.Errorf("cassette mismatch at interaction %d: %w", index, err)
+	}
+
+	tools, err := recordTools(r.tools)
+	if err != nil {
+		return nil, err
+	}
+	if err := compareTools(tools, interaction.Tools); err != nil {
+		return nil, fmt.Errorf("cassette mismatch at interaction %d: %w", index, err)
+	}
+
+	c.next += 1
+	return interaction, nil
+}
+
+// compareMessages compares normalized requests in full, so a change in a tool
+// result or the system prompt fails the replay like any other change.
+func compareMessages(got []*schema.Message, want []*schema.Message) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("%d messages, recorded %d", len(got), len(want))
+	}
+
+	for i := range got {
+		g, w := got[i], want[i]
+		if g.Role != w.Role {
+			return fmt.Errorf("message %d role %s, recorded %s", i, g.Role, w.Role)
+		}
+		if g.Content != w.Content {
+			return fmt.Errorf("message %d content %q, recorded %q", i, g.Content, w.Content)
+		}
+		if g.ToolCallID != w.ToolCallID {
+			return fmt.Errorf("message %d tool call id %s, recorded %s", i, g.ToolCallID, w.ToolCallID)
+		}
+		if len(g.ToolCalls) != len(w.ToolCalls) {
+			return fmt.Errorf("message %d has %d tool calls, recorded %d", i, len(g.ToolCalls), len(w.ToolCalls))
+		}
+		for j := range g.ToolCalls {
+			gc, wc := g.ToolCalls[j], w.ToolCalls[j]
+			if gc.ID != wc.ID || gc.Function.Name != wc.Function.Name || gc.Function.Arguments != wc.Function.Arguments {
+				return fmt.Errorf("message %d tool call %d %s(%s), recorded %s(%s)", i, j, gc.Function.Name, gc.Function.Arguments, wc.Function.Name, wc.Function.Arguments)
+			}
+		}
+		if len(g.UserInputMultiContent) != len(w.UserInputMultiContent) {
+			return fmt.Errorf("message %d has %d content parts, recorded %d", i, len(g.UserInputMultiContent), len(w.UserInputMultiContent))
+		}
+		for j := range g.UserInputMultiContent {
+			gp, wp := g.UserInputMultiContent[j], w.UserInputMultiContent[j]
+			if gp.Type != wp.Type || gp.Text != wp.Text {
+				return fmt.Errorf("message %d content part %d differs from the recording", i, j)
+			}
+		}
+	}
+
+	return nil
+}
+
+func compareTools(got []*Tool, want []*Tool) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("%d tools, recorded %d", len(got), len(want))
+	}
+
+	for i := range got {
+		if got[i].Name != want[i].Name || got[i].Description != want[i].Description {
+			return fmt.Errorf("tool %d is %s, recorded %s", i, got[i].Name, want[i].Name)
+		}
+		if !equalJSON(got[i].Parameters, want[i].Parameters) {
+			return fmt.Errorf("tool %s parameters differ from the recording", got[i].Name)
+		}
+	}
+
+	return nil
+}
+
+func equalJSON(a json.RawMessage, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+//go:embed assets/prompts/compaction.txt
+var compactionPromptContent []byte
+
+const (
+	compactionThreshold        = 0.8
+	compactionKeepRecentTurns  = 2
+	compactionToolResultLimit  = 2000
+	compactionTranscriptRatio  = 0.5
+	elidedToolResultMaxLength  = 200
+	defaultContextWindowTokens = 128_000
+)
+
+func contextWindowTokens(modelID string) int {
+	config, ok := getModelConfig(modelID)
+	if !ok {
+		return defaultContextWindowTokens
+	}
+
+	tokens, err := parseContextWindow(config.Info.ContextWindow)
+	if err != nil || tokens <= 0 {
+		return defaultContextWindowTokens
+	}
+
+	return tokens
+}
+
+func parseContextWindow(window string) (int, error) {
+	window = strings.ToLower(strings.TrimSpace(window))
+	if window == "" {
+		return 0, fmt.Errorf("context window is empty")
+	}
+
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(window, "k"):
+		multiplier = 1_000
+		window = strings.TrimSuffix(window, "k")
+	case strings.HasSuffix(window, "m"):
+		multiplier = 1_000_000
+		window = strings.TrimSuffix(window, "m")
+	}
+
+	value, err := strconv.ParseFloat(window, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid context window: %w", err)
+	}
+
+	return int(value * multiplier), nil
+}
+
+func estimateTextTokens(text string) int {
+	asciiBytes := 0
+	otherRunes := 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			asciiBytes += 1
+		} else {
+			otherRunes += 1
+		}
+	}
+
+	return (asciiBytes+3)/4 + otherRunes
+}
+
+func estimateMessagesTokens(messages []*schema.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += 4
+		total += estimateTextTokens(msg.Content)
+		total += estimateTextTokens(msg.ReasoningContent)
+		for _, tc := range msg.ToolCalls {
+			total += estimateTextTokens(tc.Function.Name)
+			total += estimateTextTokens(tc.Function.Arguments)
+		}
+		for _, part := range msg.UserInputMultiContent {
+			if part.Type == schema.ChatMessagePartTypeImageURL {
+				total += imageAttachmentTokens
+			}
+		}
+	}
+
+	return total
+}
+
+func (a *Agent) promptMessages() []*schema.Message {
+	return a.promptMessagesFor(a.config.ModelID)
+}
+
+func (a *Agent) promptMessagesFor(modelID string) []*schema.Message {
+	return applyReasoningEchoPolicy(a.compactedMessages(), reasoningEchoPolicyFor(modelID))
+}
+
+func (a *Agent) compactedMessages() []*schema.Message {
+	compaction := a.compaction
+	if compaction == nil || len(a.messages) == 0 {
+		return a.messages
+	}
+
+	summarizedUntil := min(max(compaction.SummarizedUntil, 1), len(a.messages))
+	elidedUntil := min(compaction.ElidedToolResultsUntil, len(a.messages))
+
+	prompt := make([]*schema.Message, 0, len(a.messages)-summarizedUntil+1)
+
+	system := a.messages[0]
+	if compaction.Summary != "" {
+		system = &schema.Message{
+			Role:    schema.System,
+			Content: system.Content + "\n\n## Summary of the earlier conversation\n\n" + compaction.Summary,
+		}
+	}
+	prompt = append(prompt, system)
+
+	for i := summarizedUntil; i < len(a.messages); i += 1 {
+		msg := a.messages[i]
+		if msg.Role == schema.Tool && i < elidedUntil && utf8.RuneCountInString(msg.Content) > elidedToolResultMaxLength {
+			elided := *msg
+			elided.Content = elideToolResult(msg.Content)
+			msg = &elided
+		}
+		prompt = append(prompt, msg)
+	}
+
+	return prompt
+}
+
+func elideToolResult(content string) string {
+	runes := []rune(content)
+	head := string(runes[:min(len(runes), elidedToolResultMaxLength)])
+	return fmt.Sprintf("%s\n...\n[Tool output elided to save context: %d characters omitted]", head, len(runes)-elidedToolResultMaxLength)
+}
+
+func (a *Agent) recentTurnsBoundary(keepTurns int) int {
+	seen := 0
+	for i := len(a.messages) - 1; i > 0; i -= 1 {
+		if a.messages[i].Role != schema.User {
+			continue
+		}
+		seen += 1
+		if seen == keepTurns {
+			return i
+		}
+	}
+
+	return 1
+}
+
+func (a *Agent) lastAssistantIndex() int {
+	for i := len(a.messages) - 1; i > 0; i -= 1 {
+		if a.messages[i].Role == schema.Assistant {
+			return i
+		}
+	}
+
+	return 1
+}
+
+func (a *Agent) maybeCompact(ctx context.Context) (*models.AgentContextCompacted, error) {
+	window := contextWindowTokens(a.config.ModelID)
+	limit := int(float64(window) * compactionThreshold)
+
+	before := estimateMessagesTokens(a.promptMessages())
+	if before < limit {
+		return nil, nil
+	}
+
+	compaction := a.currentCompaction()
+
+	elidedUntil := a.lastAssistantIndex()
+	if elidedUntil > compaction.ElidedToolResultsUntil {
+		compaction.ElidedToolResultsUntil = elidedUntil
+		a.compaction = compaction
+	}
+
+	if after := estimateMessagesTokens(a.promptMessages()); after < limit {
+		return &models.AgentContextCompacted{
+			TokensBefore: before,
+			TokensAfter:  after,
+			Strategy:     models.CompactionStrategyElideToolResults,
+		}, nil
+	}
+
+	if err := a.summarizeUntil(ctx, a.recentTurnsBoundary(compactionKeepRecentTurns)); err != nil {
+		return nil, err
+	}
+
+	return &models.AgentContextCompacted{
+		TokensBefore: before,
+		TokensAfter:  estimateMessagesTokens(a.promptMessages()),
+		Strategy:     models.CompactionStrategySummarize,
+	}, nil
+}
+
+func (a *Agent) Compact(ctx context.Context) (*models.AgentContextCompacted, error) {
+	if a.IsRunning() {
+		return nil, ErrThreadRunning
+	}
+
+	before := estimateMessagesTokens(a.promptMessages())
+
+	boundary := a.recentTurnsBoundary(1)
+	if err := a.summarizeUntil(ctx, boundary); err != nil {
+		return nil, err
+	}
+
+	return &models.AgentContextCompacted{
+		TokensBefore: before,
+		TokensAfter:  estimateMessagesTokens(a.promptMessages()),
+		Strategy:     models.CompactionStrategySummarize,
+	}, nil
+}
+
+func (a *Agent) forceCompact(ctx context.Context) (*models.AgentContextCompacted, error) {
+	before := estimateMessagesTokens(a.promptMessages())
+
+	compaction := a.currentCompaction()
+	elided := false
+	if elidedUntil := a.lastAssistantIndex(); elidedUntil > compaction.ElidedToolResultsUntil {
+		compaction.ElidedToolResultsUntil = elidedUntil
+		a.compaction = compaction
+		elided = true
+	}
+
+	strategy := models.CompactionStrategySummarize
+	if err := a.summarizeUntil(ctx, a.recentTurnsBoundary(1)); err != nil {
+		if !elided {
+			return nil, err
+		}
+		strategy = models.CompactionStrategyElideToolResults
+	}
+
+	return &models.AgentContextCompacted{
+		TokensBefore: before,
+		TokensAfter:  estimateMessagesTokens(a.promptMessages()),
+		Strategy:     strategy,
+	}, nil
+}
+
+func (a *Agent) Compaction() *models.ThreadCompaction {
+	return a.compaction
+}
+
+func (a *Agent) currentCompaction() *models.ThreadCompaction {
+	if a.compaction == nil {
+		return &models.ThreadCompaction{
+			SummarizedUntil: 1,
+		}
+	}
+
+	compaction := *a.compaction
+	return &compaction
+}
+
+func (a *Agent) summarizeUntil(ctx context.Context, boundary int) error {
+	compaction := a.currentCompaction()
+	if boundary <= compaction.SummarizedUntil {
+		return fmt.Errorf("no earlier messages to compact")
+	}
+
+	transcript := buildCompactionTranscript(a.messages[compaction.SummarizedUntil:boundary], contextWindowTokens(a.config.ModelID))
+
+	var input strings.Builder
+	if compaction.Summary != "" {
+		input.WriteString("Previous summary:\n")
+		input.WriteString(compaction.Summary)
+		input.WriteString("\n\n")
+	}
+	input.WriteString("Conversation:\n")
+	input.WriteString(transcript)
+
+	model, err := getModel(ctx, a.config.ModelID, a.config.WorkDir)
+	if err != nil {
+		return fmt.Errorf("failed to compact messages: %w", err)
+	}
+
+	response, err := model.Generate(ctx, []*schema.Message{
+		{
+			Role:    schema.System,
+			Content: string(compactionPromptContent),
+		},
+		{
+			Role:    schema.User,
+			Content: input.String(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compact messages: %w", err)
+	}
+	a.recordUsage(a.config.ModelID, response)
+
+	summary := strings.TrimSpace(response.Content)
+	if summary == "" {
+		return fmt.Errorf("failed to compact messages: empty summary")
+	}
+
+	compaction.Summary = summary
+	compaction.SummarizedUntil = boundary
+	compaction.ElidedToolResultsUntil = max(compaction.ElidedToolResultsUntil, boundary)
+	compaction.CompactedAt = time.Now().UnixMilli()
+	a.compaction = compaction
+
+	return nil
+}
+
+func buildCompactionTranscript(messages []*schema.Message, windowTokens int) string {
+	budget := int(float64(windowTokens) * compactionTranscriptRatio)
+
+	lines := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		var b strings.Builder
+		switch msg.Role {
+		case schema.User:
+			b.WriteString("User: ")
+			b.WriteString(msg.Content)
+		case schema.Assistant:
+			b.WriteString("Assistant: ")
+			b.WriteString(msg.Content)
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&b, "\n[Tool call] %s %s", tc.Function.Name, tc.Function.Arguments)
+			}
+		case schema.Tool:
+			b.WriteString("Tool: ")
+			content := []rune(msg.Content)
+			if len(content) > compactionToolResultLimit {
+				b.WriteString(string(content[:compactionToolResultLimit]))
+				fmt.Fprintf(&b, "\n[... %d characters truncated]", len(content)-compactionToolResultLimit)
+			} else {
+				b.WriteString(msg.Content)
+			}
+		default:
+			continue
+		}
+		lines = append(lines, b.String())
+	}
+
+	total := 0
+	start := len(lines)
+	for start > 0 {
+		tokens := estimateTextTokens(lines[start-1])
+		if total+tokens > budget {
+			break
+		}
+		total += tokens
+		start -= 1
+	}
+
+	transcript := strings.Join(lines[start:], "\n\n")
+	if start > 0 {
+		transcript = fmt.Sprintf("[%d earlier messages omitted]\n\n%s", start, transcript)
+	}
+
+	return transcript
+}
+
+func (a *Agent) clampCompaction() {
+	if a.compaction == nil {
+		return
+	}
+
+	if a.compaction.SummarizedUntil > len(a.messages) {
+		a.compaction = nil
+		return
+	}
+
+	a.compaction.ElidedToolResultsUntil = min(a.compaction.ElidedToolResultsUntil, len(a.messages))
+}
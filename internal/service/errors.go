@@ -0,0 +1,11 @@
+package service
+
+import "errors"
+
+var (
+	ErrThreadNotFound       = errors.New("thread not found")
+	ErrThreadRunning        = errors.New("thread is running")
+	ErrThreadNotRunning     = errors.New("thread is not running")
+	ErrToolApprovalNotFound = errors.New("no pending tool approval")
+	ErrToolApprovalAnswered = errors.New("tool approval already answered")
+)
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+const modelExtraKey = "alter_model"
+
+var fallbackErrorClasses = map[models.ModelErrorClass]bool{
+	models.ModelErrorClassRateLimit: true,
+	models.ModelErrorClassOverload:  true,
+	models.ModelErrorClassNetwork:   true,
+	models.ModelErrorClassAuth:      true,
+}
+
+func normalizeFallbackModels(primary string, modelIDs []string) ([]string, error) {
+	normalized := make([]string, 0, len(modelIDs))
+	for _, modelID := range modelIDs {
+		modelID = strings.TrimSpace(modelID)
+		if modelID == "" || modelID == primary || slices.Contains(normalized, modelID) {
+			continue
+		}
+		if !isModelAvailable(modelID) {
+			return nil, fmt.Errorf("fallback model is not available: %s", modelID)
+		}
+		normalized = append(normalized, modelID)
+	}
+
+	return normalized, nil
+}
+
+func (a *Agent) modelChain() []string {
+	return append([]string{a.config.ModelID}, a.config.FallbackModels...)
+}
+
+func (a *Agent) generateWithFallback(ctx context.Context, msgChan chan<- models.AgentMessage) (*schema.Message, error) {
+	chain := a.modelChain()
+	a.mu.Lock()
+	if a.modelIndex >= len(chain) {
+		a.modelIndex = 0
+	}
+	index := a.modelIndex
+	a.mu.Unlock()
+
+	for {
+		modelID := chain[index]
+		response, err := a.generateWithRetry(ctx, modelID, msgChan)
+		if err == nil {
+			if response.Extra == nil {
+				response.Extra = make(map[string]any)
+			}
+			response.Extra[modelExtraKey] = modelID
+			return response, nil
+		}
+
+		var modelErr *modelError
+		if !errors.As(err, &modelErr) || !fallbackErrorClasses[modelErr.class] {
+			return nil, err
+		}
+
+		next := a.nextFallbackModel(chain, index)
+		if next < 0 {
+			return nil, err
+		}
+
+		index = next
+		a.mu.Lock()
+		a.modelIndex = next
+		a.mu.Unlock()
+		msgChan <- models.AgentModelSwitched{
+			From:  modelID,
+			To:    chain[next],
+			Class: modelErr.class,
+			Error: modelErr.err.Error(),
+		}
+	}
+}
+
+func (a *Agent) nextFallbackModel(chain []string, index int) int {
+	needsVision := a.currentTurnHasAttachments()
+	for i := index + 1; i < len(chain); i += 1 {
+		if needsVision && !supportsVision(chain[i]) {
+			continue
+		}
+		return i
+	}
+
+	return -1
+}
+
+func messageModel(msg *schema.Message) string {
+	modelID, _ := msg.Extra[modelExtraKey].(string)
+	return modelID
+}
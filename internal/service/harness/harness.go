@@ -0,0 +1,242 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service"
+	"github.com/zjregee/alter/internal/service/cassette"
+	"github.com/zjregee/alter/internal/service/scripted"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+const (
+	dataDirEnv          = "ALTER_HOME"
+	defaultModelID      = "scripted"
+	harnessTurnInterval = time.Millisecond
+)
+
+type Harness struct {
+	Service  *service.AgentService
+	Model    *scripted.ChatModel
+	Cassette *cassette.Cassette
+	ThreadID string
+	WorkDir  string
+}
+
+type StoredMessage struct {
+	Role      schema.RoleType
+	Content   string
+	ToolCalls []string
+}
+
+// New runs against the database under ALTER_HOME, which must be set to a
+// scratch directory before the process starts so real threads are untouched.
+func New(ctx context.Context, script *scripted.Script) (*Harness, error) {
+	chatModel := scripted.NewChatModel(script)
+	service.RegisterScriptedModel(defaultModelID, chatModel)
+
+	h, err := newHarness(ctx, defaultModelID)
+	if err != nil {
+		return nil, err
+	}
+	h.Model = chatModel
+
+	return h, nil
+}
+
+func NewReplay(ctx context.Context, cassettePath string) (*Harness, error) {
+	c, err := service.UseCassette(service.CassetteModeReplay, cassettePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Interactions) == 0 {
+		return nil, fmt.Errorf("cassette %s has no interactions", cassettePath)
+	}
+
+	h, err := newHarness(ctx, c.Interactions[0].ModelID)
+	if err != nil {
+		return nil, err
+	}
+	h.Cassette = c
+
+	return h, nil
+}
+
+func newHarness(ctx context.Context, modelID string) (*Harness, error) {
+	if os.Getenv(dataDirEnv) == "" {
+		return nil, fmt.Errorf("%s must point to a scratch directory", dataDirEnv)
+	}
+
+	workDir, err := os.MkdirTemp("", "alter-harness-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work dir: %w", err)
+	}
+
+	svc, err := service.NewAgentService(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.AddWorkspace(workDir); err != nil {
+		return nil, err
+	}
+
+	threadID, err := svc.CreateThreadWithConfig(ctx, models.AgentConfig{
+		ModelID:         modelID,
+		WorkDir:         workDir,
+		RequestInterval: harnessTurnInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.UpdateToolApprovalSettings(workDir, models.ToolApprovalModeNever, nil); err != nil {
+		return nil, err
+	}
+
+	return &Harness{
+		Service:  svc,
+		ThreadID: threadID,
+		WorkDir:  workDir,
+	}, nil
+}
+
+func (h *Harness) Send(ctx context.Context, userInput string) ([]models.AgentMessage, error) {
+	msgChan, err := h.Service.StreamRequestToThread(ctx, h.ThreadID, userInput, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]models.AgentMessage, 0)
+	for msg := range msgChan {
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+func (h *Harness) StoredMessages() ([]*schema.Message, error) {
+	records, err := storage.LoadThreads()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Info.ID != h.ThreadID {
+			continue
+		}
+
+		nodes := make(map[string]*models.MessageNode, len(record.Nodes))
+		for _, node := range record.Nodes {
+			nodes[node.ID] = node
+		}
+
+		path := make([]*schema.Message, 0)
+		for id := record.ActiveLeafID; id != ""; {
+			node, ok := nodes[id]
+			if !ok {
+				return nil, fmt.Errorf("stored message not found: %s", id)
+			}
+			path = append(path, node.Message)
+			id = node.ParentID
+		}
+		slices.Reverse(path)
+
+		return path, nil
+	}
+
+	return nil, fmt.Errorf("stored thread not found: %s", h.ThreadID)
+}
+
+func (h *Harness) Close() error {
+	if err := h.Service.DeleteThread(h.ThreadID); err != nil {
+		return err
+	}
+	if err := h.Service.DeleteWorkspace(h.WorkDir); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(h.WorkDir)
+}
+
+func WithoutTypes(messages []models.AgentMessage, types ...models.AgentMessageType) []models.AgentMessage {
+	filtered := make([]models.AgentMessage, 0, len(messages))
+	for _, msg := range messages {
+		if slices.Contains(types, msg.GetType()) {
+			continue
+		}
+		filtered = append(filtered, msg)
+	}
+
+	return filtered
+}
+
+func WithoutDeltas(messages []models.AgentMessage) []models.AgentMessage {
+	return WithoutTypes(messages, models.AgentMessageTypeThoughtDelta, models.AgentMessageTypeReasoningDelta)
+}
+
+func ExpectMessages(got []models.AgentMessage, want []models.AgentMessage) error {
+	for i := 0; i < max(len(got), len(want)); i += 1 {
+		if i >= len(got) {
+			return fmt.Errorf("message %d: missing, want %s %+v", i, want[i].GetType(), want[i])
+		}
+		if i >= len(want) {
+			return fmt.Errorf("message %d: unexpected %s %+v", i, got[i].GetType(), got[i])
+		}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			return fmt.Errorf("message %d: got %s %+v, want %s %+v", i, got[i].GetType(), got[i], want[i].GetType(), want[i])
+		}
+	}
+
+	return nil
+}
+
+func ExpectStoredMessages(got []*schema.Message, want []StoredMessage) error {
+	stored := make([]StoredMessage, 0, len(got))
+	for _, msg := range got {
+		if msg.Role == schema.System {
+			continue
+		}
+
+		names := make([]string, 0, len(msg.ToolCalls))
+		for _, tc := range msg.ToolCalls {
+			names = append(names, tc.Function.Name)
+		}
+		stored = append(stored, StoredMessage{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			ToolCalls: names,
+		})
+	}
+
+	for i := 0; i < max(len(stored), len(want)); i += 1 {
+		if i >= len(stored) {
+			return fmt.Errorf("stored message %d: missing, want %s", i, formatStoredMessage(want[i]))
+		}
+		if i >= len(want) {
+			return fmt.Errorf("stored message %d: unexpected %s", i, formatStoredMessage(stored[i]))
+		}
+		if stored[i].Role != want[i].Role || stored[i].Content != want[i].Content || !slices.Equal(stored[i].ToolCalls, want[i].ToolCalls) {
+			return fmt.Errorf("stored message %d: got %s, want %s", i, formatStoredMessage(stored[i]), formatStoredMessage(want[i]))
+		}
+	}
+
+	return nil
+}
+
+func formatStoredMessage(msg StoredMessage) string {
+	if len(msg.ToolCalls) == 0 {
+		return fmt.Sprintf("%s %q", msg.Role, msg.Content)
+	}
+
+	return fmt.Sprintf("%s %q calling %s", msg.Role, msg.Content, strings.Join(msg.ToolCalls, ", "))
+}
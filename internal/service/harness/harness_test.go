@@ -0,0 +1,250 @@
+package harness
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service"
+	"github.com/zjregee/alter/internal/service/scripted"
+)
+
+func TestMain(m *testing.M) {
+	dataDir, err := os.MkdirTemp("", "alter-harness-data-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv(dataDirEnv, dataDir)
+	// bash runs login shells, so a profile in the real home would add its
+	// output to tool results and break cassette replays.
+	os.Setenv("HOME", dataDir)
+
+	code := m.Run()
+	os.RemoveAll(dataDir)
+	os.Exit(code)
+}
+
+func TestToolCallTurn(t *testing.T) {
+	script, err := scripted.ParseScript([]byte(`
+turns:
+  - content: Reading the notes.
+    tool_calls:
+      - id: call-1
+        name: read_file
+        arguments: '{"path": "notes.txt"}'
+  - content: The notes say hello.
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	h, err := New(ctx, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if err := os.WriteFile(filepath.Join(h.WorkDir, "notes.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := h.Send(ctx, "What do the notes say?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := `{"path": "notes.txt"}`
+	err = ExpectMessages(WithoutDeltas(messages), []models.AgentMessage{
+		models.AgentStartThinking{},
+		models.AgentThought{Content: "Reading the notes."},
+		models.AgentExecutingToolStart{ID: 1, Name: "read_file", Args: args},
+		models.AgentExecutingToolFinish{ID: 1, Name: "read_file", Args: args, Content: "     1\thello"},
+		models.AgentStartThinking{},
+		models.AgentThought{Content: "The notes say hello."},
+		models.AgentFinalResponse{Content: "The notes say hello."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := h.StoredMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ExpectStoredMessages(stored, []StoredMessage{
+		{Role: schema.User, Content: "What do the notes say?"},
+		{Role: schema.Assistant, Content: "Reading the notes.", ToolCalls: []string{"read_file"}},
+		{Role: schema.Tool, Content: "     1\thello"},
+		{Role: schema.Assistant, Content: "The notes say hello."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining := h.Model.Remaining(); remaining != 0 {
+		t.Fatalf("script has %d unused turns", remaining)
+	}
+}
+
+// The fixture was recorded from the scripted model in another work dir at
+// another time, which it holds as placeholders that match the replay.
+func TestReplayFixture(t *testing.T) {
+	service.RegisterScriptedModel(defaultModelID, scripted.NewChatModel(nil))
+	defer service.UseCassette("", "")
+
+	ctx := context.Background()
+	h, err := NewReplay(ctx, filepath.Join("testdata", "pwd.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	messages, err := h.Send(ctx, "Where are you working?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages = WithoutDeltas(messages)
+	var finish *models.AgentExecutingToolFinish
+	for _, msg := range messages {
+		if m, ok := msg.(models.AgentExecutingToolFinish); ok {
+			finish = &m
+		}
+	}
+	if finish == nil || !strings.Contains(finish.Content, h.WorkDir) {
+		t.Fatalf("bash did not run in the replay work dir %s: %+v", h.WorkDir, finish)
+	}
+
+	last := messages[len(messages)-1]
+	if err := ExpectMessages([]models.AgentMessage{last}, []models.AgentMessage{
+		models.AgentFinalResponse{Content: "The working directory is the workspace."},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining := h.Cassette.Remaining(); remaining != 0 {
+		t.Fatalf("cassette has %d unused interactions", remaining)
+	}
+}
+
+// A tool result that differs from the recording fails the replay instead of
+// replaying a response to a request that was never made.
+func TestReplayMismatch(t *testing.T) {
+	service.RegisterScriptedModel(defaultModelID, scripted.NewChatModel(nil))
+	defer service.UseCassette("", "")
+
+	data, err := os.ReadFile(filepath.Join("testdata", "pwd.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "pwd.json")
+	data = []byte(strings.Replace(string(data), "Exit code: 0", "Exit code: 1", 1))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	h, err := NewReplay(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	messages, err := h.Send(ctx, "Where are you working?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := messages[len(messages)-1]
+	if m, ok := last.(models.AgentError); !ok || !strings.Contains(m.Error, "cassette mismatch at interaction 1: message 3 content") {
+		t.Fatalf("replay did not fail on the changed tool result: %+v", last)
+	}
+}
+
+// steeringModel steers the thread while the model is generating, after the
+// turn has taken the notes that were already pending.
+type steeringModel struct {
+	*scripted.ChatModel
+	steer func()
+}
+
+func (m *steeringModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.steer()
+	return m.ChatModel.Stream(ctx, input, opts...)
+}
+
+func (m *steeringModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	if _, err := m.ChatModel.WithTools(tools); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func TestSteerBeforeError(t *testing.T) {
+	script, err := scripted.ParseScript([]byte(`
+turns:
+  - error: the model is gone
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	h, err := New(ctx, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	var steerErr error
+	service.RegisterScriptedModel(defaultModelID, &steeringModel{
+		ChatModel: h.Model,
+		steer: func() {
+			steerErr = h.Service.SteerThread(h.ThreadID, "Use the other file.")
+		},
+	})
+
+	messages, err := h.Send(ctx, "Summarize the notes.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steerErr != nil {
+		t.Fatal(steerErr)
+	}
+
+	messages = WithoutDeltas(messages)
+	steered, ok := messages[len(messages)-1].(models.AgentSteered)
+	if !ok || steered.Timestamp == 0 {
+		t.Fatalf("run did not keep the steering note: %+v", messages)
+	}
+	err = ExpectMessages(messages, []models.AgentMessage{
+		models.AgentStartThinking{},
+		models.AgentError{Error: "agent generation failed: unknown error: the model is gone"},
+		models.AgentSteered{Content: "Use the other file.", Timestamp: steered.Timestamp},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := h.StoredMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ExpectStoredMessages(stored, []StoredMessage{
+		{Role: schema.User, Content: "Summarize the notes."},
+		{Role: schema.User, Content: stored[len(stored)-1].Content},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(stored[len(stored)-1].Content, "\nUse the other file.") {
+		t.Fatalf("stored steering note is %q", stored[len(stored)-1].Content)
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+const (
+	hookExtraKey              = "alter_hook"
+	defaultHookTimeoutSeconds = 30
+	hookBlockExitCode         = 2
+)
+
+var hookEvents = []models.HookEvent{
+	models.HookEventPreToolUse,
+	models.HookEventPostToolUse,
+	models.HookEventTurnStart,
+	models.HookEventRunFinished,
+}
+
+type hookInput struct {
+	Event         models.HookEvent `json:"event"`
+	ThreadID      string           `json:"thread_id"`
+	WorkDir       string           `json:"work_dir"`
+	ModelID       string           `json:"model_id"`
+	Iteration     int              `json:"iteration,omitempty"`
+	ToolName      string           `json:"tool_name,omitempty"`
+	ToolCallID    string           `json:"tool_call_id,omitempty"`
+	ToolArguments string           `json:"tool_arguments,omitempty"`
+	ToolResult    string           `json:"tool_result,omitempty"`
+	ToolError     string           `json:"tool_error,omitempty"`
+	Status        string           `json:"status,omitempty"`
+	Content       string           `json:"content,omitempty"`
+}
+
+type hookOutput struct {
+	Decision  string  `json:"decision,omitempty"`
+	Reason    string  `json:"reason,omitempty"`
+	Arguments *string `json:"arguments,omitempty"`
+	Context   string  `json:"context,omitempty"`
+}
+
+type hookResult struct {
+	blocked   bool
+	reason    string
+	arguments string
+	context   []string
+}
+
+func getWorkspaceHooks(workspacePath string) (*models.WorkspaceHooks, error) {
+	hooks, err := storage.LoadWorkspaceHooks(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if hooks == nil {
+		return &models.WorkspaceHooks{
+			WorkspacePath: workspacePath,
+			Hooks:         []*models.HookConfig{},
+		}, nil
+	}
+
+	return hooks, nil
+}
+
+func updateWorkspaceHooks(workspacePath string, hooks []*models.HookConfig) error {
+	normalized := make([]*models.HookConfig, 0, len(hooks))
+	for i, hook := range hooks {
+		if hook == nil {
+			continue
+		}
+		if !slices.Contains(hookEvents, hook.Event) {
+			return fmt.Errorf("hook %d has unsupported event: %s", i, hook.Event)
+		}
+		command := strings.TrimSpace(hook.Command)
+		if command == "" {
+			return fmt.Errorf("hook %d command is required", i)
+		}
+		if hook.TimeoutSeconds < 0 {
+			return fmt.Errorf("hook %d timeout must not be negative", i)
+		}
+
+		normalized = append(normalized, &models.HookConfig{
+			Event:          hook.Event,
+			Tools:          hook.Tools,
+			Command:        command,
+			TimeoutSeconds: hook.TimeoutSeconds,
+		})
+	}
+
+	return storage.SaveWorkspaceHooks(&models.WorkspaceHooks{
+		WorkspacePath: workspacePath,
+		Hooks:         normalized,
+	})
+}
+
+func hookMatches(hook *models.HookConfig, event models.HookEvent, toolName string) bool {
+	if hook.Event != event {
+		return false
+	}
+	if toolName == "" || len(hook.Tools) == 0 {
+		return true
+	}
+
+	return slices.Contains(hook.Tools, toolName)
+}
+
+// runHooks runs every matching hook in order. A blocking hook stops the chain,
+// and rewritten arguments are passed on to the hooks after it. Pre-tool hooks
+// fail closed: a hook that cannot give a decision may be the one that would
+// have blocked the call, so the call is blocked instead.
+func (a *Agent) runHooks(ctx context.Context, input hookInput) hookResult {
+	var result hookResult
+
+	settings, err := getWorkspaceHooks(a.config.WorkDir)
+	if err != nil {
+		fmt.Printf("Failed to load hooks for %s: %v\n", a.config.WorkDir, err)
+		if input.Event == models.HookEventPreToolUse {
+			result.blocked = true
+			result.reason = fmt.Sprintf("hooks could not be loaded: %v", err)
+		}
+		return result
+	}
+
+	input.ThreadID = a.id
+	input.WorkDir = a.config.WorkDir
+	if input.ModelID == "" {
+		input.ModelID = a.config.ModelID
+	}
+
+	for _, hook := range settings.Hooks {
+		if !hookMatches(hook, input.Event, input.ToolName) {
+			continue
+		}
+
+		output, err := runHookCommand(ctx, hook, a.config.WorkDir, input)
+		if err != nil {
+			fmt.Printf("Failed to run %s hook %q: %v\n", input.Event, hook.Command, err)
+			if input.Event == models.HookEventPreToolUse {
+				result.blocked = true
+				result.reason = fmt.Sprintf("hook %q failed: %v", hook.Command, err)
+				return result
+			}
+			continue
+		}
+
+		if output.Context != "" {
+			result.context = append(result.context, output.Context)
+		}
+		if output.Decision == "block" {
+			result.blocked = true
+			result.reason = output.Reason
+			return result
+		}
+		if output.Arguments != nil {
+			result.arguments = *output.Arguments
+			input.ToolArguments = *output.Arguments
+		}
+	}
+
+	return result
+}
+
+func runHookCommand(ctx context.Context, hook *models.HookConfig, workDir string, input hookInput) (*hookOutput, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hook input: %w", err)
+	}
+
+	timeoutSeconds := hook.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultHookTimeoutSeconds
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "bash", "-c", hook.Command)
+	cmd.Dir = workDir
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == hookBlockExitCode {
+			return &hookOutput{
+				Decision: "block",
+				Reason:   strings.TrimSpace(stderr.String()),
+			}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("hook timed out after %ds", timeoutSeconds)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+
+	output := &hookOutput{}
+	if data := bytes.TrimSpace(stdout.Bytes()); len(data) > 0 {
+		if err := json.Unmarshal(data, output); err != nil {
+			return nil, fmt.Errorf("failed to parse hook output: %w", err)
+		}
+	}
+
+	return output, nil
+}
+
+func formatHookBlockedResult(reason string) string {
+	if reason == "" {
+		return "A workspace hook blocked this tool call."
+	}
+
+	return "A workspace hook blocked this tool call: " + reason
+}
+
+func formatHookContext(context []string) string {
+	if len(context) == 0 {
+		return ""
+	}
+
+	return "[Context added by a workspace hook]\n" + strings.Join(context, "\n")
+}
+
+func (a *Agent) injectHookContext(event models.HookEvent, context []string, msgChan chan<- models.AgentMessage) {
+	content := formatHookContext(context)
+	if content == "" {
+		return
+	}
+
+	a.appendMessage(&schema.Message{
+		Role:    schema.User,
+		Content: content,
+		Extra: map[string]any{
+			hookExtraKey: string(event),
+		},
+	})
+
+	msgChan <- models.AgentHookContext{
+		Event:   event,
+		Content: strings.Join(context, "\n"),
+	}
+}
+
+func messageHookEvent(msg *schema.Message) models.HookEvent {
+	event, _ := msg.Extra[hookExtraKey].(string)
+	return models.HookEvent(event)
+}
+
+func (a *Agent) forwardRun(inChan <-chan models.AgentMessage, outChan chan<- models.AgentMessage) {
+	defer close(outChan)
+
+	var last models.AgentMessage
+	for msg := range inChan {
+		// Steering notes left over when a run stops come after the message
+		// that ended it.
+		if _, ok := msg.(models.AgentSteered); !ok {
+			last = msg
+		}
+		outChan <- msg
+	}
+
+	input := hookInput{Event: models.HookEventRunFinished}
+	switch m := last.(type) {
+	case models.AgentFinalResponse:
+		input.Content = m.Content
+	case models.AgentError:
+		input.Content = m.Error
+	}
+	if last != nil {
+		input.Status = string(last.GetType())
+	}
+	a.runHooks(context.Background(), input)
+}
+
+func (s *AgentService) GetWorkspaceHooks(workspacePath string) (*models.WorkspaceHooks, error) {
+	return getWorkspaceHooks(workspacePath)
+}
+
+func (s *AgentService) UpdateWorkspaceHooks(workspacePath string, hooks []*models.HookConfig) error {
+	return updateWorkspaceHooks(workspacePath, hooks)
+}
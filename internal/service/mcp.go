@@ -0,0 +1,628 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/mcp"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+const (
+	mcpStartTimeout  = 30 * time.Second
+	mcpMaxRestarts   = 3
+	mcpRestartWindow = 5 * time.Minute
+)
+
+var mcpClientInfo = mcp.Implementation{Name: "alter", Version: "0.1.0"}
+
+type mcpServer struct {
+	scope  string
+	config models.MCPServerConfig
+
+	startMu sync.Mutex
+
+	mu           sync.Mutex
+	client       *mcp.Client
+	tools        []mcp.Tool
+	attempted    bool
+	failed       bool
+	lastErr      string
+	restarts     int
+	restartTimes []time.Time
+}
+
+var mcpServers = struct {
+	mu      sync.Mutex
+	servers map[string]*mcpServer
+}{servers: make(map[string]*mcpServer)}
+
+func mcpServerKey(scope string, name string) string {
+	return scope + "\x00" + name
+}
+
+// getMCPServer returns the running entry for a config, replacing it when the
+// config has changed since it was started.
+func getMCPServer(scope string, config *models.MCPServerConfig) *mcpServer {
+	mcpServers.mu.Lock()
+	defer mcpServers.mu.Unlock()
+
+	key := mcpServerKey(scope, config.Name)
+	if server, ok := mcpServers.servers[key]; ok {
+		if reflect.DeepEqual(server.config, *config) {
+			return server
+		}
+		go server.stop()
+	}
+
+	server := &mcpServer{scope: scope, config: *config}
+	mcpServers.servers[key] = server
+
+	return server
+}
+
+func stopMCPServers(scope string, keep []*models.MCPServerConfig) {
+	mcpServers.mu.Lock()
+	var stopped []*mcpServer
+	for key, server := range mcpServers.servers {
+		if server.scope != scope {
+			continue
+		}
+		kept := slices.ContainsFunc(keep, func(config *models.MCPServerConfig) bool {
+			return reflect.DeepEqual(server.config, *config)
+		})
+		if !kept {
+			delete(mcpServers.servers, key)
+			stopped = append(stopped, server)
+		}
+	}
+	mcpServers.mu.Unlock()
+
+	for _, server := range stopped {
+		server.stop()
+	}
+}
+
+func stopAllMCPServers() {
+	mcpServers.mu.Lock()
+	servers := make([]*mcpServer, 0, len(mcpServers.servers))
+	for _, server := range mcpServers.servers {
+		servers = append(servers, server)
+	}
+	clear(mcpServers.servers)
+	mcpServers.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.stop()
+		}()
+	}
+	wg.Wait()
+}
+
+func newMCPClient(scope string, config *models.MCPServerConfig) *mcp.Client {
+	if config.Transport == models.MCPTransportHTTP {
+		return mcp.NewHTTPClient(config.URL, config.Headers, mcpClientInfo)
+	}
+
+	return mcp.NewStdioClient(config.Command, config.Args, config.Env, scope, mcpClientInfo)
+}
+
+// ensure returns a live client, starting the server on first use and
+// restarting it after a crash. Too many restarts within mcpRestartWindow mark
+// the server failed until it is restarted by hand.
+func (s *mcpServer) ensure(ctx context.Context) (*mcp.Client, error) {
+	s.startMu.Lock()
+	defer s.startMu.Unlock()
+
+	s.mu.Lock()
+	client := s.client
+	if client != nil && client.Alive() {
+		s.mu.Unlock()
+		if client.ToolsChanged() {
+			if err := s.refreshTools(ctx, client); err != nil {
+				return nil, err
+			}
+		}
+		return client, nil
+	}
+	if s.failed {
+		defer s.mu.Unlock()
+		return nil, fmt.Errorf("MCP server %s failed: %s", s.config.Name, s.lastErr)
+	}
+	if client != nil {
+		if err := client.Err(); err != nil && !errors.Is(err, mcp.ErrClosed) {
+			s.lastErr = err.Error()
+		}
+	}
+	if s.attempted {
+		now := time.Now()
+		s.restartTimes = slices.DeleteFunc(s.restartTimes, func(t time.Time) bool {
+			return now.Sub(t) > mcpRestartWindow
+		})
+		s.restartTimes = append(s.restartTimes, now)
+		s.restarts += 1
+		if len(s.restartTimes) > mcpMaxRestarts {
+			s.failed = true
+			s.client = nil
+			s.lastErr = fmt.Sprintf("restarted %d times within %s: %s", mcpMaxRestarts, mcpRestartWindow, s.lastErr)
+			defer s.mu.Unlock()
+			return nil, fmt.Errorf("MCP server %s failed: %s", s.config.Name, s.lastErr)
+		}
+	}
+	s.attempted = true
+	s.mu.Unlock()
+
+	client = newMCPClient(s.scope, &s.config)
+	startCtx, cancel := context.WithTimeout(ctx, mcpStartTimeout)
+	defer cancel()
+
+	if err := client.Start(startCtx); err != nil {
+		s.mu.Lock()
+		s.client = nil
+		s.lastErr = err.Error()
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to start MCP server %s: %w", s.config.Name, err)
+	}
+
+	s.mu.Lock()
+	s.client = client
+	s.lastErr = ""
+	s.mu.Unlock()
+
+	if err := s.refreshTools(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (s *mcpServer) refreshTools(ctx context.Context, client *mcp.Client) error {
+	var tools []mcp.Tool
+	if client.Capabilities.Tools != nil {
+		var err error
+		tools, err = client.ListTools(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list MCP server %s tools: %w", s.config.Name, err)
+		}
+	}
+
+	s.mu.Lock()
+	s.tools = tools
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *mcpServer) stop() {
+	s.startMu.Lock()
+	defer s.startMu.Unlock()
+
+	s.mu.Lock()
+	client := s.client
+	s.client = nil
+	s.tools = nil
+	s.mu.Unlock()
+
+	if client != nil {
+		if err := client.Close(); err != nil {
+			fmt.Printf("Failed to stop MCP server %s: %v\n", s.config.Name, err)
+		}
+	}
+}
+
+func (s *mcpServer) restart(ctx context.Context) error {
+	s.stop()
+
+	s.mu.Lock()
+	s.failed = false
+	s.lastErr = ""
+	s.restartTimes = nil
+	if s.attempted {
+		s.restarts += 1
+	}
+	s.attempted = false
+	s.mu.Unlock()
+
+	_, err := s.ensure(ctx)
+	return err
+}
+
+func (s *mcpServer) listTools(ctx context.Context) (*mcp.Client, []mcp.Tool, error) {
+	client, err := s.ensure(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return client, slices.Clone(s.tools), nil
+}
+
+// withClient retries once on a fresh connection when the request could not be
+// sent, which covers servers that exited between runs and expired HTTP
+// sessions. Requests lost mid-call are not retried since they may have run.
+func withClient[T any](ctx context.Context, s *mcpServer, call func(*mcp.Client) (T, error)) (T, error) {
+	client, err := s.ensure(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	result, err := call(client)
+	if !errors.Is(err, mcp.ErrClosed) {
+		return result, err
+	}
+
+	client, err = s.ensure(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return call(client)
+}
+
+func (s *mcpServer) status() *models.MCPServerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &models.MCPServerStatus{
+		Name:      s.config.Name,
+		Scope:     s.scope,
+		State:     models.MCPServerStateStopped,
+		Error:     s.lastErr,
+		ToolCount: len(s.tools),
+		Restarts:  s.restarts,
+	}
+	switch {
+	case s.failed:
+		status.State = models.MCPServerStateFailed
+	case s.client != nil && s.client.Alive():
+		status.State = models.MCPServerStateRunning
+	case s.client != nil:
+		status.State = models.MCPServerStateFailed
+		if err := s.client.Err(); err != nil {
+			status.Error = err.Error()
+		}
+	case s.lastErr != "":
+		status.State = models.MCPServerStateFailed
+	}
+
+	return status
+}
+
+type scopedMCPServerConfig struct {
+	scope  string
+	config *models.MCPServerConfig
+}
+
+// mcpServerConfigs merges global servers with those of a workspace; a
+// workspace server replaces a global one with the same name.
+func mcpServerConfigs(workspacePath string) ([]scopedMCPServerConfig, error) {
+	scopes := []string{""}
+	if workspacePath != "" {
+		scopes = append(scopes, workspacePath)
+	}
+
+	var configs []scopedMCPServerConfig
+	for _, scope := range scopes {
+		settings, err := storage.LoadMCPServerSettings(scope)
+		if err != nil {
+			return nil, err
+		}
+		if settings == nil {
+			continue
+		}
+
+		for _, config := range settings.Servers {
+			configs = slices.DeleteFunc(configs, func(c scopedMCPServerConfig) bool {
+				return c.config.Name == config.Name
+			})
+			configs = append(configs, scopedMCPServerConfig{scope: scope, config: config})
+		}
+	}
+
+	return configs, nil
+}
+
+func enabledMCPServers(workspacePath string) ([]*mcpServer, error) {
+	configs, err := mcpServerConfigs(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*mcpServer, 0, len(configs))
+	for _, c := range configs {
+		if c.config.Disabled {
+			continue
+		}
+		servers = append(servers, getMCPServer(c.scope, c.config))
+	}
+
+	return servers, nil
+}
+
+func findMCPServer(workspacePath string, name string) (*mcpServer, error) {
+	servers, err := enabledMCPServers(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range servers {
+		if server.config.Name == name {
+			return server, nil
+		}
+	}
+
+	return nil, fmt.Errorf("MCP server not found: %s", name)
+}
+
+func getMCPServerSettings(workspacePath string) (*models.MCPServerSettings, error) {
+	settings, err := storage.LoadMCPServerSettings(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		return &models.MCPServerSettings{
+			WorkspacePath: workspacePath,
+			Servers:       []*models.MCPServerConfig{},
+		}, nil
+	}
+
+	return settings, nil
+}
+
+func updateMCPServerSettings(workspacePath string, servers []*models.MCPServerConfig) error {
+	normalized := make([]*models.MCPServerConfig, 0, len(servers))
+	names := make(map[string]struct{}, len(servers))
+	for i, server := range servers {
+		if server == nil {
+			continue
+		}
+
+		config, err := normalizeMCPServerConfig(server)
+		if err != nil {
+			return fmt.Errorf("MCP server %d: %w", i, err)
+		}
+		if _, ok := names[config.Name]; ok {
+			return fmt.Errorf("MCP server name is duplicated: %s", config.Name)
+		}
+		names[config.Name] = struct{}{}
+		normalized = append(normalized, config)
+	}
+
+	if err := storage.SaveMCPServerSettings(&models.MCPServerSettings{
+		WorkspacePath: workspacePath,
+		Servers:       normalized,
+	}); err != nil {
+		return err
+	}
+
+	stopMCPServers(workspacePath, normalized)
+	return nil
+}
+
+func normalizeMCPServerConfig(server *models.MCPServerConfig) (*models.MCPServerConfig, error) {
+	config := *server
+	config.Name = strings.TrimSpace(config.Name)
+	config.Command = strings.TrimSpace(config.Command)
+	config.URL = strings.TrimSpace(config.URL)
+
+	if config.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if config.Transport == "" {
+		config.Transport = models.MCPTransportStdio
+	}
+
+	switch config.Transport {
+	case models.MCPTransportStdio:
+		if config.Command == "" {
+			return nil, fmt.Errorf("command is required")
+		}
+		config.URL = ""
+		config.Headers = nil
+	case models.MCPTransportHTTP:
+		u, err := url.Parse(config.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL: %s", config.URL)
+		}
+		config.Command = ""
+		config.Args = nil
+		config.Env = nil
+	default:
+		return nil, fmt.Errorf("unsupported transport: %s", config.Transport)
+	}
+
+	return &config, nil
+}
+
+func deleteWorkspaceMCPServers(workspacePath string) error {
+	if err := storage.DeleteMCPServerSettings(workspacePath); err != nil {
+		return err
+	}
+
+	stopMCPServers(workspacePath, nil)
+	return nil
+}
+
+func (s *AgentService) GetMCPServers(workspacePath string) (*models.MCPServerSettings, error) {
+	return getMCPServerSettings(workspacePath)
+}
+
+func (s *AgentService) UpdateMCPServers(workspacePath string, servers []*models.MCPServerConfig) error {
+	return updateMCPServerSettings(workspacePath, servers)
+}
+
+func (s *AgentService) GetMCPServerStatuses(workspacePath string) ([]*models.MCPServerStatus, error) {
+	configs, err := mcpServerConfigs(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*models.MCPServerStatus, 0, len(configs))
+	for _, c := range configs {
+		if c.config.Disabled {
+			statuses = append(statuses, &models.MCPServerStatus{
+				Name:  c.config.Name,
+				Scope: c.scope,
+				State: models.MCPServerStateDisabled,
+			})
+			continue
+		}
+		statuses = append(statuses, getMCPServer(c.scope, c.config).status())
+	}
+
+	return statuses, nil
+}
+
+func (s *AgentService) RestartMCPServer(ctx context.Context, workspacePath string, name string) error {
+	server, err := findMCPServer(workspacePath, name)
+	if err != nil {
+		return err
+	}
+
+	return server.restart(ctx)
+}
+
+func (s *AgentService) StopMCPServers() {
+	stopAllMCPServers()
+}
+
+func (s *AgentService) ListMCPPrompts(ctx context.Context, workspacePath string) ([]*models.MCPPrompt, error) {
+	servers, err := enabledMCPServers(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := []*models.MCPPrompt{}
+	for _, server := range servers {
+		result, err := withClient(ctx, server, func(client *mcp.Client) ([]mcp.Prompt, error) {
+			if client.Capabilities.Prompts == nil {
+				return nil, nil
+			}
+			return client.ListPrompts(ctx)
+		})
+		if err != nil {
+			fmt.Printf("Failed to list MCP server %s prompts: %v\n", server.config.Name, err)
+			continue
+		}
+
+		for _, prompt := range result {
+			arguments := make([]*models.MCPPromptArgument, 0, len(prompt.Arguments))
+			for _, argument := range prompt.Arguments {
+				arguments = append(arguments, &models.MCPPromptArgument{
+					Name:        argument.Name,
+					Description: argument.Description,
+					Required:    argument.Required,
+				})
+			}
+			prompts = append(prompts, &models.MCPPrompt{
+				Server:      server.config.Name,
+				Name:        prompt.Name,
+				Title:       prompt.Title,
+				Description: prompt.Description,
+				Arguments:   arguments,
+			})
+		}
+	}
+
+	return prompts, nil
+}
+
+// GetMCPPrompt renders a prompt to plain text so it can be used as user input.
+func (s *AgentService) GetMCPPrompt(ctx context.Context, workspacePath string, serverName string, name string, arguments map[string]string) (string, error) {
+	server, err := findMCPServer(workspacePath, serverName)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := withClient(ctx, server, func(client *mcp.Client) (*mcp.GetPromptResult, error) {
+		return client.GetPrompt(ctx, name, arguments)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get MCP prompt %s: %w", name, err)
+	}
+
+	parts := make([]string, 0, len(result.Messages))
+	for _, message := range result.Messages {
+		if text := formatMCPContent([]mcp.Content{message.Content}); text != "" {
+			parts = append(parts, text)
+		}
+	}
+
+	return strings.Join(parts, "\n\n"), nil
+}
+
+func (s *AgentService) ListMCPResources(ctx context.Context, workspacePath string) ([]*models.MCPResource, error) {
+	servers, err := enabledMCPServers(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := []*models.MCPResource{}
+	for _, server := range servers {
+		result, err := withClient(ctx, server, func(client *mcp.Client) ([]mcp.Resource, error) {
+			if client.Capabilities.Resources == nil {
+				return nil, nil
+			}
+			return client.ListResources(ctx)
+		})
+		if err != nil {
+			fmt.Printf("Failed to list MCP server %s resources: %v\n", server.config.Name, err)
+			continue
+		}
+
+		for _, resource := range result {
+			resources = append(resources, &models.MCPResource{
+				Server:      server.config.Name,
+				URI:         resource.URI,
+				Name:        resource.Name,
+				Title:       resource.Title,
+				Description: resource.Description,
+				MIMEType:    resource.MIMEType,
+			})
+		}
+	}
+
+	return resources, nil
+}
+
+func (s *AgentService) ReadMCPResource(ctx context.Context, workspacePath string, serverName string, uri string) (string, error) {
+	server, err := findMCPServer(workspacePath, serverName)
+	if err != nil {
+		return "", err
+	}
+
+	return readMCPResource(ctx, server, uri)
+}
+
+func readMCPResource(ctx context.Context, server *mcpServer, uri string) (string, error) {
+	contents, err := withClient(ctx, server, func(client *mcp.Client) ([]mcp.ResourceContents, error) {
+		return client.ReadResource(ctx, uri)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read MCP resource %s: %w", uri, err)
+	}
+
+	parts := make([]string, 0, len(contents))
+	for _, content := range contents {
+		parts = append(parts, formatMCPResourceContents(&content))
+	}
+
+	return strings.Join(parts, "\n\n"), nil
+}
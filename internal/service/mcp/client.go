@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when a message could not be sent because the
+// connection is gone, so the request is known not to have reached the server.
+var ErrClosed = errors.New("MCP connection closed")
+
+type transport interface {
+	start(ctx context.Context, handle func(*message)) error
+	send(ctx context.Context, msg *message) error
+	close() error
+	done() <-chan struct{}
+	err() error
+}
+
+type Client struct {
+	transport  transport
+	clientInfo Implementation
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[string]chan *message
+
+	toolsChanged atomic.Bool
+
+	ServerInfo   Implementation
+	Capabilities ServerCapabilities
+	Instructions string
+}
+
+func newClient(t transport, clientInfo Implementation) *Client {
+	return &Client{
+		transport:  t,
+		clientInfo: clientInfo,
+		pending:    make(map[string]chan *message),
+	}
+}
+
+// Start connects the transport and performs the initialize handshake. The
+// context only bounds the handshake; the connection lives until Close.
+func (c *Client) Start(ctx context.Context) error {
+	if err := c.transport.start(ctx, c.handle); err != nil {
+		return err
+	}
+
+	var result initializeResult
+	err := c.call(ctx, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		ClientInfo:      c.clientInfo,
+	}, &result)
+	if err != nil {
+		_ = c.transport.close()
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	c.ServerInfo = result.ServerInfo
+	c.Capabilities = result.Capabilities
+	c.Instructions = result.Instructions
+	if t, ok := c.transport.(*httpTransport); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		_ = c.transport.close()
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+func (c *Client) Done() <-chan struct{} {
+	return c.transport.done()
+}
+
+func (c *Client) Err() error {
+	return c.transport.err()
+}
+
+func (c *Client) Alive() bool {
+	select {
+	case <-c.transport.done():
+		return false
+	default:
+		return true
+	}
+}
+
+// ToolsChanged reports whether the server announced a new tool list since
+// the last call to ListTools.
+func (c *Client) ToolsChanged() bool {
+	return c.toolsChanged.Load()
+}
+
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	c.toolsChanged.Store(false)
+
+	var tools []Tool
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", cursorParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	cursor := ""
+	for {
+		var result listResourcesResult
+		if err := c.call(ctx, "resources/list", cursorParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		resources = append(resources, result.Resources...)
+		if result.NextCursor == "" {
+			return resources, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var result readResourceResult
+	if err := c.call(ctx, "resources/read", map[string]string{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Contents, nil
+}
+
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var prompts []Prompt
+	cursor := ""
+	for {
+		var result listPromptsResult
+		if err := c.call(ctx, "prompts/list", cursorParams{Cursor: cursor}, &result); err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, result.Prompts...)
+		if result.NextCursor == "" {
+			return prompts, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) (*GetPromptResult, error) {
+	var result GetPromptResult
+	params := map[string]any{"name": name}
+	if len(arguments) > 0 {
+		params["arguments"] = arguments
+	}
+	if err := c.call(ctx, "prompts/get", params, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	requestID := c.nextID.Add(1)
+	id := strconv.FormatInt(requestID, 10)
+	msg, err := newMessage(method, params)
+	if err != nil {
+		return err
+	}
+	msg.ID = json.RawMessage(id)
+
+	respChan := make(chan *message, 1)
+	c.mu.Lock()
+	c.pending[id] = respChan
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.transport.send(ctx, msg); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		_ = c.notify(context.Background(), "notifications/cancelled", map[string]any{
+			"requestId": requestID,
+			"reason":    ctx.Err().Error(),
+		})
+		return ctx.Err()
+	case <-c.transport.done():
+		if err := c.transport.err(); err != nil && !errors.Is(err, ErrClosed) {
+			return fmt.Errorf("connection lost during %s: %w", method, err)
+		}
+		return fmt.Errorf("connection lost during %s", method)
+	case resp := <-respChan:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+	}
+}
+
+func (c *Client) notify(ctx context.Context, method string, params any) error {
+	msg, err := newMessage(method, params)
+	if err != nil {
+		return err
+	}
+
+	return c.transport.send(ctx, msg)
+}
+
+func (c *Client) handle(msg *message) {
+	switch {
+	case msg.isResponse():
+		c.mu.Lock()
+		respChan, ok := c.pending[string(msg.ID)]
+		c.mu.Unlock()
+		if ok {
+			respChan <- msg
+		}
+	case msg.isRequest():
+		reply := &message{JSONRPC: jsonrpcVersion, ID: msg.ID}
+		if msg.Method == "ping" {
+			reply.Result = json.RawMessage("{}")
+		} else {
+			reply.Error = &RPCError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+		}
+		go func() {
+			_ = c.transport.send(context.Background(), reply)
+		}()
+	case msg.Method == "notifications/tools/list_changed":
+		c.toolsChanged.Store(true)
+	}
+}
+
+func newMessage(method string, params any) (*message, error) {
+	msg := &message{
+		JSONRPC: jsonrpcVersion,
+		Method:  method,
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s params: %w", method, err)
+		}
+		msg.Params = data
+	}
+
+	return msg, nil
+}
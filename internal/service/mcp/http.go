@@ -0,0 +1,232 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxHTTPErrorBody   = 4 * 1024
+	httpCloseTimeout   = 5 * time.Second
+	sessionIDHeader    = "Mcp-Session-Id"
+	protocolHeader     = "MCP-Protocol-Version"
+	eventStreamContent = "text/event-stream"
+)
+
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	handle  func(*message)
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+
+	doneChan  chan struct{}
+	closeOnce sync.Once
+	errMu     sync.Mutex
+	exitErr   error
+}
+
+func NewHTTPClient(url string, headers map[string]string, clientInfo Implementation) *Client {
+	return newClient(&httpTransport{
+		url:      url,
+		headers:  headers,
+		client:   &http.Client{},
+		doneChan: make(chan struct{}),
+	}, clientInfo)
+}
+
+func (t *httpTransport) start(ctx context.Context, handle func(*message)) error {
+	t.handle = handle
+	return nil
+}
+
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.protocolVersion = version
+}
+
+func (t *httpTransport) send(ctx context.Context, msg *message) error {
+	select {
+	case <-t.doneChan:
+		return ErrClosed
+	default:
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, "+eventStreamContent)
+	sessionID := t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if id := resp.Header.Get(sessionIDHeader); id != "" && msg.Method == "initialize" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode == http.StatusNotFound && sessionID != "" {
+		resp.Body.Close()
+		t.finish(fmt.Errorf("session %s expired", sessionID))
+		return ErrClosed
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHTTPErrorBody))
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if resp.StatusCode == http.StatusAccepted || !msg.isRequest() {
+		resp.Body.Close()
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == eventStreamContent {
+		go t.readEvents(resp.Body)
+		return nil
+	}
+
+	defer resp.Body.Close()
+	var reply message
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	t.handle(&reply)
+
+	return nil
+}
+
+func (t *httpTransport) setHeaders(req *http.Request) string {
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessionID != "" {
+		req.Header.Set(sessionIDHeader, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(protocolHeader, t.protocolVersion)
+	}
+
+	return t.sessionID
+}
+
+func (t *httpTransport) readEvents(body io.ReadCloser) {
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStdioMessageSize)
+
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			t.dispatchEvent(data.String())
+			data.Reset()
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+	}
+	t.dispatchEvent(data.String())
+}
+
+func (t *httpTransport) dispatchEvent(data string) {
+	if strings.TrimSpace(data) == "" {
+		return
+	}
+
+	var msg message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return
+	}
+	t.handle(&msg)
+}
+
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	alive := true
+	select {
+	case <-t.doneChan:
+		alive = false
+	default:
+	}
+	t.finish(ErrClosed)
+
+	if !alive || sessionID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpCloseTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (t *httpTransport) finish(err error) {
+	t.closeOnce.Do(func() {
+		t.errMu.Lock()
+		t.exitErr = err
+		t.errMu.Unlock()
+		close(t.doneChan)
+	})
+}
+
+func (t *httpTransport) done() <-chan struct{} {
+	return t.doneChan
+}
+
+func (t *httpTransport) err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+
+	return t.exitErr
+}
@@ -0,0 +1,324 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	codeParseError    = -32700
+	codeInvalidParams = -32602
+	codeInternalError = -32603
+)
+
+var supportedProtocolVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (*CallToolResult, error)
+
+type PromptProvider struct {
+	List func(ctx context.Context) ([]Prompt, error)
+	Get  func(ctx context.Context, name string, arguments map[string]string) (*GetPromptResult, error)
+}
+
+type ResourceProvider struct {
+	List func(ctx context.Context) ([]Resource, error)
+	Read func(ctx context.Context, uri string) ([]ResourceContents, error)
+}
+
+type serverTool struct {
+	tool    Tool
+	handler ToolHandler
+}
+
+// Server answers MCP requests over a newline-delimited JSON stream. Requests
+// are handled concurrently so a long tool call does not block pings or
+// cancellations.
+type Server struct {
+	info         Implementation
+	instructions string
+	tools        []serverTool
+	prompts      *PromptProvider
+	resources    *ResourceProvider
+
+	writeMu sync.Mutex
+	w       io.Writer
+
+	mu       sync.Mutex
+	inFlight map[string]context.CancelFunc
+}
+
+func NewServer(info Implementation, instructions string) *Server {
+	return &Server{
+		info:         info,
+		instructions: instructions,
+		inFlight:     make(map[string]context.CancelFunc),
+	}
+}
+
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	s.tools = append(s.tools, serverTool{tool: tool, handler: handler})
+}
+
+func (s *Server) SetPrompts(provider *PromptProvider) {
+	s.prompts = provider
+}
+
+func (s *Server) SetResources(provider *ResourceProvider) {
+	s.resources = provider
+}
+
+// Serve reads requests from r until it is closed or ctx is done, and waits for
+// in-flight requests before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.w = w
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxStdioMessageSize)
+		for scanner.Scan() {
+			lines <- slices.Clone(scanner.Bytes())
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case line = <-lines:
+		}
+
+		if strings.TrimSpace(string(line)) == "" {
+			continue
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			s.reply(&message{ID: json.RawMessage("null")}, nil, &RPCError{Code: codeParseError, Message: "parse error"})
+			continue
+		}
+
+		if !msg.isRequest() {
+			s.handleNotification(&msg)
+			continue
+		}
+
+		reqCtx, reqCancel := context.WithCancel(ctx)
+		s.mu.Lock()
+		s.inFlight[string(msg.ID)] = reqCancel
+		s.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.inFlight, string(msg.ID))
+				s.mu.Unlock()
+				reqCancel()
+			}()
+
+			result, rpcErr := s.handleRequest(reqCtx, &msg)
+			if reqCtx.Err() != nil && ctx.Err() == nil {
+				return
+			}
+			s.reply(&msg, result, rpcErr)
+		}()
+	}
+}
+
+func (s *Server) handleNotification(msg *message) {
+	if msg.Method != "notifications/cancelled" {
+		return
+	}
+
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	cancel, ok := s.inFlight[string(params.RequestID)]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (s *Server) handleRequest(ctx context.Context, msg *message) (any, *RPCError) {
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(msg.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		tools := make([]Tool, 0, len(s.tools))
+		for _, t := range s.tools {
+			tools = append(tools, t.tool)
+		}
+		return listToolsResult{Tools: tools}, nil
+	case "tools/call":
+		return s.callTool(ctx, msg.Params)
+	case "prompts/list":
+		if s.prompts == nil {
+			break
+		}
+		prompts, err := s.prompts.List(ctx)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		return listPromptsResult{Prompts: prompts}, nil
+	case "prompts/get":
+		if s.prompts == nil {
+			break
+		}
+		var params struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		result, err := s.prompts.Get(ctx, params.Name, params.Arguments)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		return result, nil
+	case "resources/list":
+		if s.resources == nil {
+			break
+		}
+		resources, err := s.resources.List(ctx)
+		if err != nil {
+			return nil, internalError(err)
+		}
+		return listResourcesResult{Resources: resources}, nil
+	case "resources/read":
+		if s.resources == nil {
+			break
+		}
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		contents, err := s.resources.Read(ctx, params.URI)
+		if err != nil {
+			return nil, invalidParams(err)
+		}
+		return readResourceResult{Contents: contents}, nil
+	}
+
+	return nil, &RPCError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+func (s *Server) initialize(raw json.RawMessage) (any, *RPCError) {
+	var params initializeParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+
+	version := ProtocolVersion
+	if slices.Contains(supportedProtocolVersions, params.ProtocolVersion) {
+		version = params.ProtocolVersion
+	}
+
+	capabilities := ServerCapabilities{Tools: &struct{}{}}
+	if s.prompts != nil {
+		capabilities.Prompts = &struct{}{}
+	}
+	if s.resources != nil {
+		capabilities.Resources = &struct{}{}
+	}
+
+	return initializeResult{
+		ProtocolVersion: version,
+		Capabilities:    capabilities,
+		ServerInfo:      s.info,
+		Instructions:    s.instructions,
+	}, nil
+}
+
+// callTool reports handler failures as tool results with IsError set, so the
+// calling model sees them, and keeps protocol errors for unknown tools.
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (any, *RPCError) {
+	var params callToolParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, invalidParams(err)
+	}
+
+	idx := slices.IndexFunc(s.tools, func(t serverTool) bool {
+		return t.tool.Name == params.Name
+	})
+	if idx < 0 {
+		return nil, &RPCError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+
+	arguments := params.Arguments
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+
+	result, err := s.tools[idx].handler(ctx, arguments)
+	if err != nil {
+		return &CallToolResult{
+			Content: []Content{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}, nil
+	}
+
+	return result, nil
+}
+
+func (s *Server) reply(req *message, result any, rpcErr *RPCError) {
+	resp := &message{JSONRPC: jsonrpcVersion, ID: req.ID, Error: rpcErr}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = internalError(err)
+		} else {
+			resp.Result = data
+		}
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	_, _ = s.w.Write(append(data, '\n'))
+}
+
+func TextResult(text string) *CallToolResult {
+	return &CallToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+func invalidParams(err error) *RPCError {
+	return &RPCError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func internalError(err error) *RPCError {
+	return &RPCError{Code: codeInternalError, Message: err.Error()}
+}
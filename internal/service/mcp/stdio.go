@@ -0,0 +1,210 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxStdioMessageSize = 16 * 1024 * 1024
+	stderrTailSize      = 4 * 1024
+	stdioStopTimeout    = 2 * time.Second
+)
+
+type stdioTransport struct {
+	command string
+	args    []string
+	env     []string
+	dir     string
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu   sync.Mutex
+	closing   atomic.Bool
+	doneChan  chan struct{}
+	closeOnce sync.Once
+	errMu     sync.Mutex
+	exitErr   error
+}
+
+func NewStdioClient(command string, args []string, env map[string]string, dir string, clientInfo Implementation) *Client {
+	environ := os.Environ()
+	for key, value := range env {
+		environ = append(environ, key+"="+value)
+	}
+
+	return newClient(&stdioTransport{
+		command:  command,
+		args:     args,
+		env:      environ,
+		dir:      dir,
+		stderr:   &tailBuffer{limit: stderrTailSize},
+		doneChan: make(chan struct{}),
+	}, clientInfo)
+}
+
+func (t *stdioTransport) start(ctx context.Context, handle func(*message)) error {
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Env = t.env
+	cmd.Dir = t.dir
+	cmd.Stderr = t.stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", t.command, err)
+	}
+	t.cmd = cmd
+	t.stdin = stdin
+
+	go t.read(stdout, handle)
+
+	return nil
+}
+
+func (t *stdioTransport) read(stdout io.Reader, handle func(*message)) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStdioMessageSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var msg message
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			continue
+		}
+		handle(&msg)
+	}
+
+	readErr := scanner.Err()
+	waitErr := t.cmd.Wait()
+	if t.closing.Load() {
+		t.finish(ErrClosed)
+		return
+	}
+
+	t.finish(func() error {
+		stderr := strings.TrimSpace(t.stderr.String())
+		switch {
+		case readErr != nil:
+			return fmt.Errorf("failed to read from server: %w", readErr)
+		case waitErr != nil && stderr != "":
+			return fmt.Errorf("server exited: %v: %s", waitErr, stderr)
+		case waitErr != nil:
+			return fmt.Errorf("server exited: %w", waitErr)
+		case stderr != "":
+			return fmt.Errorf("server exited: %s", stderr)
+		default:
+			return fmt.Errorf("server exited")
+		}
+	}())
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	select {
+	case <-t.doneChan:
+		return ErrClosed
+	default:
+	}
+
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to server: %w", err)
+	}
+
+	return nil
+}
+
+func (t *stdioTransport) close() error {
+	if t.cmd == nil || t.cmd.Process == nil {
+		t.finish(ErrClosed)
+		return nil
+	}
+
+	t.closing.Store(true)
+	_ = t.stdin.Close()
+	select {
+	case <-t.doneChan:
+		return nil
+	case <-time.After(stdioStopTimeout):
+	}
+
+	if err := t.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to stop server: %w", err)
+	}
+	<-t.doneChan
+
+	return nil
+}
+
+func (t *stdioTransport) finish(err error) {
+	t.closeOnce.Do(func() {
+		t.errMu.Lock()
+		t.exitErr = err
+		t.errMu.Unlock()
+		close(t.doneChan)
+	})
+}
+
+func (t *stdioTransport) done() <-chan struct{} {
+	return t.doneChan
+}
+
+func (t *stdioTransport) err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+
+	return t.exitErr
+}
+
+type tailBuffer struct {
+	mu    sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.data)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/eino-contrib/jsonschema"
+
+	"github.com/zjregee/alter/internal/service/mcp"
+)
+
+const (
+	mcpToolPrefix    = "mcp__"
+	mcpToolNameLimit = 64
+
+	mcpListResourcesTool = "list_resources"
+	mcpReadResourceTool  = "read_resource"
+)
+
+type mcpTool struct {
+	server *mcpServer
+	name   string
+	info   *schema.ToolInfo
+}
+
+func (t *mcpTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.info, nil
+}
+
+func (t *mcpTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if strings.TrimSpace(argumentsInJSON) == "" {
+		argumentsInJSON = "{}"
+	}
+	if !json.Valid([]byte(argumentsInJSON)) {
+		return "", fmt.Errorf("arguments must be a JSON object")
+	}
+
+	result, err := withClient(ctx, t.server, func(client *mcp.Client) (*mcp.CallToolResult, error) {
+		return client.CallTool(ctx, t.name, json.RawMessage(argumentsInJSON))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	content := formatMCPContent(result.Content)
+	if content == "" && len(result.StructuredContent) > 0 {
+		content = string(result.StructuredContent)
+	}
+	if result.IsError {
+		if content == "" {
+			content = "MCP tool reported an error"
+		}
+		return "", errors.New(content)
+	}
+
+	return content, nil
+}
+
+type mcpResourceTool struct {
+	server *mcpServer
+	read   bool
+	info   *schema.ToolInfo
+}
+
+func (t *mcpResourceTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return t.info, nil
+}
+
+func (t *mcpResourceTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if t.read {
+		var params struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal([]byte(argumentsInJSON), &params); err != nil {
+			return "", fmt.Errorf("failed to parse arguments: %w", err)
+		}
+		if strings.TrimSpace(params.URI) == "" {
+			return "", fmt.Errorf("uri must be provided")
+		}
+		return readMCPResource(ctx, t.server, params.URI)
+	}
+
+	resources, err := withClient(ctx, t.server, func(client *mcp.Client) ([]mcp.Resource, error) {
+		return client.ListResources(ctx)
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resources) == 0 {
+		return "No resources available.", nil
+	}
+
+	lines := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		line := fmt.Sprintf("- %s (%s)", resource.URI, resource.Name)
+		if resource.MIMEType != "" {
+			line += " [" + resource.MIMEType + "]"
+		}
+		if resource.Description != "" {
+			line += ": " + resource.Description
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// loadMCPTools starts the servers configured for a workspace and returns their
+// tools under mcp__<server>__<tool> names. Servers that fail are logged and
+// left out so one broken server does not stop the run.
+func loadMCPTools(ctx context.Context, workDir string) ([]*schema.ToolInfo, map[string]tool.InvokableTool) {
+	servers, err := enabledMCPServers(workDir)
+	if err != nil {
+		fmt.Printf("Failed to load MCP servers: %v\n", err)
+		return nil, nil
+	}
+
+	var infos []*schema.ToolInfo
+	toolsMap := make(map[string]tool.InvokableTool)
+	addTool := func(info *schema.ToolInfo, t tool.InvokableTool) {
+		if _, exists := toolsMap[info.Name]; exists {
+			fmt.Printf("Failed to add MCP tool %s: name is already used\n", info.Name)
+			return
+		}
+		infos = append(infos, info)
+		toolsMap[info.Name] = t
+	}
+
+	for _, server := range servers {
+		client, tools, err := server.listTools(ctx)
+		if err != nil {
+			fmt.Printf("Failed to load MCP server %s tools: %v\n", server.config.Name, err)
+			continue
+		}
+
+		for _, t := range tools {
+			description := t.Description
+			if description == "" {
+				description = t.Title
+			}
+			info := &schema.ToolInfo{
+				Name:        mcpToolName(server.config.Name, t.Name),
+				Desc:        description,
+				ParamsOneOf: mcpToolParams(t.InputSchema),
+			}
+			addTool(info, &mcpTool{server: server, name: t.Name, info: info})
+		}
+
+		if client.Capabilities.Resources == nil {
+			continue
+		}
+		listInfo := &schema.ToolInfo{
+			Name:        mcpToolName(server.config.Name, mcpListResourcesTool),
+			Desc:        fmt.Sprintf("Lists the resources provided by the %s MCP server.", server.config.Name),
+			ParamsOneOf: mcpToolParams(nil),
+		}
+		addTool(listInfo, &mcpResourceTool{server: server, info: listInfo})
+		readInfo := &schema.ToolInfo{
+			Name: mcpToolName(server.config.Name, mcpReadResourceTool),
+			Desc: fmt.Sprintf("Reads a resource provided by the %s MCP server.", server.config.Name),
+			ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+				"uri": {
+					Type:     schema.String,
+					Desc:     "The URI of the resource to read.",
+					Required: true,
+				},
+			}),
+		}
+		addTool(readInfo, &mcpResourceTool{server: server, read: true, info: readInfo})
+	}
+
+	return infos, toolsMap
+}
+
+func mcpToolName(server string, name string) string {
+	sanitize := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+				return r
+			}
+			return '_'
+		}, s)
+	}
+
+	full := mcpToolPrefix + sanitize(server) + "__" + sanitize(name)
+	if len(full) > mcpToolNameLimit {
+		full = full[:mcpToolNameLimit]
+	}
+
+	return full
+}
+
+// mcpToolParams falls back to an empty object schema when a server sends one
+// the model API would reject, so the tool stays callable.
+func mcpToolParams(inputSchema json.RawMessage) *schema.ParamsOneOf {
+	var s jsonschema.Schema
+	if len(inputSchema) == 0 || json.Unmarshal(inputSchema, &s) != nil || s.Type != "object" {
+		s = jsonschema.Schema{Type: "object"}
+	}
+
+	return schema.NewParamsOneOfByJSONSchema(&s)
+}
+
+func formatMCPContent(contents []mcp.Content) string {
+	parts := make([]string, 0, len(contents))
+	for _, content := range contents {
+		switch content.Type {
+		case "text":
+			parts = append(parts, content.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s: %s]", content.Type, content.MIMEType))
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s]", content.URI))
+		case "resource":
+			if content.Resource != nil {
+				parts = append(parts, formatMCPResourceContents(content.Resource))
+			}
+		}
+	}
+
+	return strings.Join(slices.DeleteFunc(parts, func(part string) bool {
+		return part == ""
+	}), "\n")
+}
+
+func formatMCPResourceContents(content *mcp.ResourceContents) string {
+	if content.Text != "" || content.Blob == "" {
+		return content.Text
+	}
+
+	return fmt.Sprintf("[binary resource: %s, %s]", content.URI, content.MIMEType)
+}
+
+func (a *Agent) refreshTools(ctx context.Context) {
+	infos, toolsMap := loadMCPTools(ctx, a.config.WorkDir)
+
+	allInfos := slices.Clone(a.builtinTools)
+	allToolsMap := make(map[string]tool.InvokableTool, len(a.builtinToolsMap)+len(toolsMap))
+	for name, t := range a.builtinToolsMap {
+		allToolsMap[name] = t
+	}
+	for _, info := range infos {
+		if _, exists := allToolsMap[info.Name]; exists {
+			continue
+		}
+		allInfos = append(allInfos, info)
+		allToolsMap[info.Name] = toolsMap[info.Name]
+	}
+
+	a.mu.Lock()
+	a.tools = allInfos
+	a.toolsMap = allToolsMap
+	a.mu.Unlock()
+}
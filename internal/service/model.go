@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/cloudwego/eino-ext/components/model/ark"
 	"github.com/cloudwego/eino-ext/components/model/deepseek"
@@ -31,6 +32,7 @@ const (
 	ByteDanceModelProvider  = "ByteDance"
 	MoonshotModelProvider   = "Moonshot"
 	OpenRouterModelProvider = "OpenRouter"
+	ScriptedModelProvider   = "Scripted"
 )
 
 const (
@@ -41,36 +43,17 @@ const (
 )
 
 var (
-	DeepSeekModelAPIKey   string
-	ByteDanceModelAPIKey  string
-	MoonshotModelAPIKey   string
-	OpenRouterModelAPIKey string
-)
-
-func init() {
-	DeepSeekModelAPIKey = os.Getenv("DEEPSEEK_API_KEY")
-	ByteDanceModelAPIKey = os.Getenv("BYTE_DANCE_API_KEY")
-	MoonshotModelAPIKey = os.Getenv("MOONSHOT_API_KEY")
+	DeepSeekModelAPIKey   = os.Getenv("DEEPSEEK_API_KEY")
+	ByteDanceModelAPIKey  = os.Getenv("BYTE_DANCE_API_KEY")
+	MoonshotModelAPIKey   = os.Getenv("MOONSHOT_API_KEY")
 	OpenRouterModelAPIKey = os.Getenv("OPENROUTER_API_KEY")
-
-	if DeepSeekModelAPIKey == "" {
-		panic("DEEPSEEK_API_KEY is not set")
-	}
-	if ByteDanceModelAPIKey == "" {
-		panic("BYTE_DANCE_API_KEY is not set")
-	}
-	if MoonshotModelAPIKey == "" {
-		panic("MOONSHOT_API_KEY is not set")
-	}
-	if OpenRouterModelAPIKey == "" {
-		panic("OPENROUTER_API_KEY is not set")
-	}
-}
+)
 
 type ModelConfig struct {
-	Info    *models.ModelInfo
-	APIKey  string
-	BaseURL string
+	Info          *models.ModelInfo
+	APIKey        string
+	BaseURL       string
+	ReasoningEcho reasoningEchoPolicy
 }
 
 var availableModels = map[string]*ModelConfig{
@@ -91,15 +74,17 @@ var availableModels = map[string]*ModelConfig{
 			Provider:      DeepSeekModelProvider,
 			ContextWindow: "128k",
 		},
-		APIKey:  DeepSeekModelAPIKey,
-		BaseURL: DeepSeekModelBaseURL,
+		APIKey:        DeepSeekModelAPIKey,
+		BaseURL:       DeepSeekModelBaseURL,
+		ReasoningEcho: reasoningEchoCurrentTurn,
 	},
 	DoubaoSeed18251215ModelID: {
 		Info: &models.ModelInfo{
-			ID:            DoubaoSeed18251215ModelID,
-			Name:          "doubao-seed-1.8",
-			Provider:      ByteDanceModelProvider,
-			ContextWindow: "256k",
+			ID:             DoubaoSeed18251215ModelID,
+			Name:           "doubao-seed-1.8",
+			Provider:       ByteDanceModelProvider,
+			ContextWindow:  "256k",
+			SupportsVision: true,
 		},
 		APIKey:  ByteDanceModelAPIKey,
 		BaseURL: ByteDanceModelBaseURL,
@@ -121,15 +106,17 @@ var availableModels = map[string]*ModelConfig{
 			Provider:      MoonshotModelProvider,
 			ContextWindow: "256k",
 		},
-		APIKey:  MoonshotModelAPIKey,
-		BaseURL: MoonshotModelBaseURL,
+		APIKey:        MoonshotModelAPIKey,
+		BaseURL:       MoonshotModelBaseURL,
+		ReasoningEcho: reasoningEchoCurrentTurn,
 	},
 	XGrok41FastModelID: {
 		Info: &models.ModelInfo{
-			ID:            XGrok41FastModelID,
-			Name:          "grok-4.1-fast",
-			Provider:      OpenRouterModelProvider,
-			ContextWindow: "2M",
+			ID:             XGrok41FastModelID,
+			Name:           "grok-4.1-fast",
+			Provider:       OpenRouterModelProvider,
+			ContextWindow:  "2M",
+			SupportsVision: true,
 		},
 		APIKey:  OpenRouterModelAPIKey,
 		BaseURL: OpenRouterModelBaseURL,
@@ -156,8 +143,37 @@ var availableModels = map[string]*ModelConfig{
 	},
 }
 
+var scriptedModels = map[string]model.ToolCallingChatModel{}
+
+// modelsMu guards availableModels and scriptedModels, since scripted models
+// can be registered while agents look models up.
+var modelsMu sync.RWMutex
+
+func RegisterScriptedModel(modelID string, chatModel model.ToolCallingChatModel) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	scriptedModels[modelID] = chatModel
+	availableModels[modelID] = &ModelConfig{
+		Info: &models.ModelInfo{
+			ID:            modelID,
+			Name:          modelID,
+			Provider:      ScriptedModelProvider,
+			ContextWindow: "128k",
+		},
+	}
+}
+
+func getModelConfig(modelID string) (*ModelConfig, bool) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	config, ok := availableModels[modelID]
+	return config, ok
+}
+
 func getDefaultModelInfo() *models.ModelInfo {
-	if config, ok := availableModels[defaultModelID]; ok {
+	if config, ok := getModelConfig(defaultModelID); ok {
 		return config.Info
 	}
 
@@ -165,6 +181,9 @@ func getDefaultModelInfo() *models.ModelInfo {
 }
 
 func getAvailableModelInfos() []*models.ModelInfo {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
 	models := make([]*models.ModelInfo, 0, len(availableModels))
 	for _, model := range availableModels {
 		models = append(models, model.Info)
@@ -174,34 +193,78 @@ func getAvailableModelInfos() []*models.ModelInfo {
 }
 
 func isModelAvailable(modelID string) bool {
-	_, ok := availableModels[modelID]
+	_, ok := getModelConfig(modelID)
 	return ok
 }
 
-func getModel(ctx context.Context, modelID string) (model.ToolCallingChatModel, error) {
-	config, ok := availableModels[modelID]
+func supportsVision(modelID string) bool {
+	config, ok := getModelConfig(modelID)
+	if !ok {
+		return false
+	}
+
+	return config.Info.SupportsVision
+}
+
+// getModel returns the model to call for a request made in workDir, which
+// cassettes use to tell the work dir apart from the rest of the request.
+func getModel(ctx context.Context, modelID string, workDir string) (model.ToolCallingChatModel, error) {
+	config, ok := getModelConfig(modelID)
 	if !ok {
 		return nil, fmt.Errorf("model not found: %s", modelID)
 	}
 
+	if replayer := cassetteReplayer(modelID, workDir); replayer != nil {
+		return replayer, nil
+	}
+
+	chatModel, err := newProviderModel(ctx, modelID, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return recordWithCassette(modelID, workDir, chatModel), nil
+}
+
+func newProviderModel(ctx context.Context, modelID string, config *ModelConfig) (model.ToolCallingChatModel, error) {
+	if config.Info.Provider == ScriptedModelProvider {
+		modelsMu.RLock()
+		defer modelsMu.RUnlock()
+
+		chatModel, ok := scriptedModels[modelID]
+		if !ok {
+			return nil, fmt.Errorf("scripted model not registered: %s", modelID)
+		}
+		return chatModel, nil
+	}
+
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("API key for %s model %s is not set", config.Info.Provider, modelID)
+	}
+
 	switch config.Info.Provider {
 	case DeepSeekModelProvider:
 		return deepseek.NewChatModel(ctx, &deepseek.ChatModelConfig{
-			APIKey:  config.APIKey,
-			BaseURL: config.BaseURL,
-			Model:   modelID,
+			APIKey:     config.APIKey,
+			BaseURL:    config.BaseURL,
+			Model:      modelID,
+			HTTPClient: modelHTTPClient,
 		})
 	case ByteDanceModelProvider:
+		retryTimes := 0
 		return ark.NewChatModel(ctx, &ark.ChatModelConfig{
-			APIKey:  config.APIKey,
-			BaseURL: config.BaseURL,
-			Model:   modelID,
+			APIKey:     config.APIKey,
+			BaseURL:    config.BaseURL,
+			Model:      modelID,
+			HTTPClient: modelHTTPClient,
+			RetryTimes: &retryTimes,
 		})
 	case MoonshotModelProvider, OpenRouterModelProvider:
 		return openai.NewChatModel(ctx, &openai.ChatModelConfig{
-			APIKey:  config.APIKey,
-			BaseURL: config.BaseURL,
-			Model:   modelID,
+			APIKey:     config.APIKey,
+			BaseURL:    config.BaseURL,
+			Model:      modelID,
+			HTTPClient: modelHTTPClient,
 		})
 	default:
 	}
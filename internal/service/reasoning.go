@@ -0,0 +1,85 @@
+package service
+
+import (
+	"github.com/cloudwego/eino/schema"
+)
+
+type reasoningEchoPolicy int
+
+const (
+	reasoningEchoNone reasoningEchoPolicy = iota
+	reasoningEchoCurrentTurn
+	reasoningEchoAll
+)
+
+var reasoningExtraKeys = []string{
+	"reasoning-content",
+	"ark-reasoning-content",
+	"_eino_deepseek_reasoning_content",
+}
+
+func reasoningEchoPolicyFor(modelID string) reasoningEchoPolicy {
+	config, ok := getModelConfig(modelID)
+	if !ok {
+		return reasoningEchoNone
+	}
+
+	return config.ReasoningEcho
+}
+
+func applyReasoningEchoPolicy(messages []*schema.Message, policy reasoningEchoPolicy) []*schema.Message {
+	if policy == reasoningEchoAll {
+		return messages
+	}
+
+	keepFrom := len(messages)
+	if policy == reasoningEchoCurrentTurn {
+		for i := len(messages) - 1; i >= 0; i -= 1 {
+			if messages[i].Role == schema.User && !isInjectedMessage(messages[i]) {
+				keepFrom = i
+				break
+			}
+		}
+	}
+
+	result := make([]*schema.Message, len(messages))
+	for i, msg := range messages {
+		if i >= keepFrom || msg.Role != schema.Assistant || !hasReasoning(msg) {
+			result[i] = msg
+			continue
+		}
+		result[i] = stripReasoning(msg)
+	}
+
+	return result
+}
+
+func hasReasoning(msg *schema.Message) bool {
+	if msg.ReasoningContent != "" {
+		return true
+	}
+	for _, key := range reasoningExtraKeys {
+		if _, ok := msg.Extra[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stripReasoning(msg *schema.Message) *schema.Message {
+	stripped := *msg
+	stripped.ReasoningContent = ""
+
+	if len(msg.Extra) > 0 {
+		stripped.Extra = make(map[string]any, len(msg.Extra))
+		for k, v := range msg.Extra {
+			stripped.Extra[k] = v
+		}
+		for _, key := range reasoningExtraKeys {
+			delete(stripped.Extra, key)
+		}
+	}
+
+	return &stripped
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	deepseekapi "github.com/cohesion-org/deepseek-go"
+	openaiapi "github.com/meguminnnnnnnnn/go-openai"
+	arkapi "github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+const maxRetryAfter = 2 * time.Minute
+
+type retryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var retryPolicies = map[models.ModelErrorClass]retryPolicy{
+	models.ModelErrorClassRateLimit: {
+		MaxRetries: 5,
+		BaseDelay:  2 * time.Second,
+		MaxDelay:   time.Minute,
+	},
+	models.ModelErrorClassOverload: {
+		MaxRetries: 4,
+		BaseDelay:  5 * time.Second,
+		MaxDelay:   time.Minute,
+	},
+	models.ModelErrorClassNetwork: {
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+		MaxDelay:   15 * time.Second,
+	},
+	models.ModelErrorClassContextOverflow: {
+		MaxRetries: 1,
+	},
+}
+
+type modelError struct {
+	class models.ModelErrorClass
+	err   error
+}
+
+func (e *modelError) Error() string {
+	return fmt.Sprintf("%s error: %v", e.class, e.err)
+}
+
+func (e *modelError) Unwrap() error {
+	return e.err
+}
+
+func classifyModelError(err error) models.ModelErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return models.ModelErrorClassCancelled
+	}
+
+	if class := classifyStatusCode(modelErrorStatusCode(err), err.Error()); class != "" {
+		return class
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return models.ModelErrorClassNetwork
+	}
+
+	return classifyErrorMessage(err.Error())
+}
+
+func modelErrorStatusCode(err error) int {
+	var deepseekErr *deepseekapi.APIError
+	if errors.As(err, &deepseekErr) {
+		return deepseekErr.StatusCode
+	}
+	var deepseekValueErr deepseekapi.APIError
+	if errors.As(err, &deepseekValueErr) {
+		return deepseekValueErr.StatusCode
+	}
+
+	var openaiErr *openaiapi.APIError
+	if errors.As(err, &openaiErr) {
+		return openaiErr.HTTPStatusCode
+	}
+	var openaiRequestErr *openaiapi.RequestError
+	if errors.As(err, &openaiRequestErr) {
+		return openaiRequestErr.HTTPStatusCode
+	}
+
+	var arkErr *arkapi.APIError
+	if errors.As(err, &arkErr) {
+		return arkErr.HTTPStatusCode
+	}
+	var arkRequestErr *arkapi.RequestError
+	if errors.As(err, &arkRequestErr) {
+		return arkRequestErr.HTTPStatusCode
+	}
+
+	return 0
+}
+
+func classifyStatusCode(statusCode int, message string) models.ModelErrorClass {
+	switch {
+	case statusCode == 0:
+		return ""
+	case statusCode == http.StatusTooManyRequests:
+		if isQuotaExhausted(message) {
+			return models.ModelErrorClassAuth
+		}
+		return models.ModelErrorClassRateLimit
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden || statusCode == http.StatusPaymentRequired:
+		return models.ModelErrorClassAuth
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return models.ModelErrorClassContextOverflow
+	case statusCode == http.StatusServiceUnavailable || statusCode == 529:
+		return models.ModelErrorClassOverload
+	case statusCode >= 500:
+		return models.ModelErrorClassNetwork
+	case statusCode >= 400:
+		if isContextOverflow(message) {
+			return models.ModelErrorClassContextOverflow
+		}
+		return models.ModelErrorClassBadRequest
+	default:
+		return ""
+	}
+}
+
+func classifyErrorMessage(message string) models.ModelErrorClass {
+	lower := strings.ToLower(message)
+	switch {
+	case isContextOverflow(lower):
+		return models.ModelErrorClassContextOverflow
+	case isQuotaExhausted(lower):
+		return models.ModelErrorClassAuth
+	case strings.Contains(lower, "429") || strings.Contains(lower, "rate limit") || strings.Contains(lower, "too many requests"):
+		return models.ModelErrorClassRateLimit
+	case strings.Contains(lower, "overloaded") || strings.Contains(lower, "server is busy") || strings.Contains(lower, "503"):
+		return models.ModelErrorClassOverload
+	case strings.Contains(lower, "unauthorized") || strings.Contains(lower, "api key") || strings.Contains(lower, "authentication"):
+		return models.ModelErrorClassAuth
+	case strings.Contains(lower, "connection reset") || strings.Contains(lower, "connection refused") ||
+		strings.Contains(lower, "timeout") || strings.Contains(lower, "eof") || strings.Contains(lower, "broken pipe"):
+		return models.ModelErrorClassNetwork
+	default:
+		return models.ModelErrorClassUnknown
+	}
+}
+
+func isContextOverflow(message string) bool {
+	lower := strings.ToLower(message)
+	for _, keyword := range []string{
+		"context length",
+		"context_length",
+		"context window",
+		"maximum context",
+		"too many tokens",
+		"prompt is too long",
+		"input is too long",
+		"exceeds the model",
+		"reduce the length",
+	} {
+		if strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isQuotaExhausted(message string) bool {
+	lower := strings.ToLower(message)
+	return strings.Contains(lower, "insufficient_quota") ||
+		strings.Contains(lower, "insufficient balance") ||
+		strings.Contains(lower, "quota exceeded") ||
+		strings.Contains(lower, "billing")
+}
+
+func retryDelay(policy retryPolicy, attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, maxRetryAfter)
+	}
+	if policy.BaseDelay <= 0 {
+		return 0
+	}
+
+	backoff := float64(policy.BaseDelay) * math.Pow(2, float64(attempt))
+	backoff = min(backoff, float64(policy.MaxDelay))
+
+	half := backoff / 2
+	return time.Duration(half + rand.Float64()*half)
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type retryAfterHint struct {
+	mu    sync.Mutex
+	value time.Duration
+}
+
+func (h *retryAfterHint) set(d time.Duration) {
+	h.mu.Lock()
+	h.value = d
+	h.mu.Unlock()
+}
+
+func (h *retryAfterHint) take() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	d := h.value
+	h.value = 0
+	return d
+}
+
+type retryAfterHintKey struct{}
+
+func withRetryAfterHint(ctx context.Context) (context.Context, *retryAfterHint) {
+	hint := &retryAfterHint{}
+	return context.WithValue(ctx, retryAfterHintKey{}, hint), hint
+}
+
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if hint, ok := req.Context().Value(retryAfterHintKey{}).(*retryAfterHint); ok {
+			hint.set(parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+		}
+	}
+
+	return resp, nil
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+
+	return 0
+}
+
+var modelHTTPClient = &http.Client{
+	Transport: &retryAfterTransport{
+		base: http.DefaultTransport,
+	},
+}
+
+func (a *Agent) generateWithRetry(ctx context.Context, modelID string, msgChan chan<- models.AgentMessage) (*schema.Message, error) {
+	retries := make(map[models.ModelErrorClass]int)
+
+	for {
+		hintCtx, hint := withRetryAfterHint(ctx)
+		response, err := a.generate(hintCtx, modelID, msgChan)
+		if err == nil {
+			return response, nil
+		}
+
+		class := classifyModelError(err)
+		if ctx.Err() != nil {
+			class = models.ModelErrorClassCancelled
+		}
+
+		policy, retryable := retryPolicies[class]
+		if !retryable || retries[class] >= policy.MaxRetries {
+			return nil, &modelError{
+				class: class,
+				err:   err,
+			}
+		}
+
+		if class == models.ModelErrorClassContextOverflow {
+			if _, compactErr := a.forceCompact(ctx); compactErr != nil {
+				return nil, &modelError{
+					class: class,
+					err:   fmt.Errorf("%w (compaction failed: %v)", err, compactErr),
+				}
+			}
+		}
+
+		delay := retryDelay(policy, retries[class], hint.take())
+		retries[class] += 1
+
+		msgChan <- models.AgentRetrying{
+			Class:      class,
+			Attempt:    retries[class],
+			MaxRetries: policy.MaxRetries,
+			DelayMs:    delay.Milliseconds(),
+			Error:      err.Error(),
+		}
+
+		if err := sleepWithContext(ctx, delay); err != nil {
+			return nil, &modelError{
+				class: models.ModelErrorClassCancelled,
+				err:   err,
+			}
+		}
+	}
+}
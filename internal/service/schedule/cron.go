@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchYears bounds Next for expressions such as "0 0 30 2 *" that never
+// match.
+const maxSearchYears = 5
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type field struct {
+	name string
+	min  int
+	max  int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+type Cron struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	anyDOM      bool
+	anyDOW      bool
+}
+
+// ParseCron accepts the standard five-field syntax (minute, hour, day of
+// month, month, day of week) with lists, ranges and steps, plus the usual
+// @daily style macros. Day of week 7 is Sunday, like 0.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression must have %d fields: %q", len(fields), expr)
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	daysOfWeek := sets[4]
+	if daysOfWeek&(1<<7) != 0 {
+		daysOfWeek |= 1
+	}
+
+	return &Cron{
+		minutes:     sets[0],
+		hours:       sets[1],
+		daysOfMonth: sets[2],
+		months:      sets[3],
+		daysOfWeek:  daysOfWeek,
+		anyDOM:      strings.HasPrefix(parts[2], "*"),
+		anyDOW:      strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseField(value string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid %s step: %q", f.name, item)
+			}
+			step = n
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			lo, hi, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseValue(lo, f); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(hi, f); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid %s range: %q", f.name, item)
+			}
+		default:
+			n, err := parseValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			start = n
+			if !hasStep {
+				end = n
+			}
+		}
+
+		for n := start; n <= end; n += step {
+			set |= 1 << n
+		}
+	}
+
+	return set, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("invalid %s: %q (must be %d-%d)", f.name, value, f.min, f.max)
+	}
+
+	return n, nil
+}
+
+// Next returns the first matching minute strictly after t, in t's location,
+// or the zero time if the expression never matches.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if c.months&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hours&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minutes&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchesDay follows cron's rule that a restricted day of month and day of
+// week match when either one does.
+func (c *Cron) matchesDay(t time.Time) bool {
+	dom := c.daysOfMonth&(1<<t.Day()) != 0
+	dow := c.daysOfWeek&(1<<int(t.Weekday())) != 0
+
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/schedule"
+	"github.com/zjregee/alter/internal/service/storage"
+)
+
+const (
+	schedulerTickInterval = 30 * time.Second
+	missedRunGrace        = 2 * time.Minute
+)
+
+type scheduledRunHandler func(job *models.ScheduledJob, threadID string, msgChan <-chan models.AgentMessage)
+
+type scheduler struct {
+	mu         sync.Mutex
+	started    bool
+	wake       chan struct{}
+	running    map[string]bool
+	runHandler scheduledRunHandler
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		wake:    make(chan struct{}, 1),
+		running: make(map[string]bool),
+	}
+}
+
+func (s *scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func normalizeScheduledJob(job *models.ScheduledJob) error {
+	job.Name = strings.TrimSpace(job.Name)
+	job.Prompt = strings.TrimSpace(job.Prompt)
+	job.ThreadID = strings.TrimSpace(job.ThreadID)
+	job.Cron = strings.TrimSpace(job.Cron)
+
+	if job.Prompt == "" {
+		return fmt.Errorf("scheduled job prompt is required")
+	}
+
+	switch job.Kind {
+	case models.ScheduleKindOnce:
+		if job.RunAt <= 0 {
+			return fmt.Errorf("one-shot job run time is required")
+		}
+		job.Cron = ""
+	case models.ScheduleKindCron:
+		if _, err := schedule.ParseCron(job.Cron); err != nil {
+			return err
+		}
+		job.RunAt = 0
+	default:
+		return fmt.Errorf("unsupported schedule kind: %s", job.Kind)
+	}
+
+	switch job.MissedRunPolicy {
+	case "":
+		job.MissedRunPolicy = models.MissedRunPolicySkip
+	case models.MissedRunPolicySkip, models.MissedRunPolicyCatchUp:
+	default:
+		return fmt.Errorf("unsupported missed run policy: %s", job.MissedRunPolicy)
+	}
+
+	return nil
+}
+
+func nextRunAt(job *models.ScheduledJob, after time.Time) int64 {
+	if !job.Enabled {
+		return 0
+	}
+
+	if job.Kind == models.ScheduleKindOnce {
+		return job.RunAt
+	}
+
+	cron, err := schedule.ParseCron(job.Cron)
+	if err != nil {
+		return 0
+	}
+	next := cron.Next(after)
+	if next.IsZero() {
+		return 0
+	}
+
+	return next.UnixMilli()
+}
+
+func (s *AgentService) ListScheduledJobs() ([]*models.ScheduledJob, error) {
+	jobs, err := storage.LoadScheduledJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(jobs, func(x, y *models.ScheduledJob) int {
+		return cmp.Or(cmp.Compare(x.CreatedAt, y.CreatedAt), strings.Compare(x.ID, y.ID))
+	})
+
+	return jobs, nil
+}
+
+func (s *AgentService) CreateScheduledJob(job *models.ScheduledJob) (*models.ScheduledJob, error) {
+	if job == nil {
+		return nil, fmt.Errorf("scheduled job is required")
+	}
+
+	created := *job
+	if err := normalizeScheduledJob(&created); err != nil {
+		return nil, err
+	}
+	if err := s.validateScheduledJobTarget(&created); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	created.ID = GenerateScheduledJobID()
+	created.NextRunAt = nextRunAt(&created, now)
+	created.LastRunAt = 0
+	created.LastThreadID = ""
+	created.LastError = ""
+	created.CreatedAt = now.UnixMilli()
+	created.UpdatedAt = now.UnixMilli()
+
+	s.scheduler.mu.Lock()
+	err := storage.SaveScheduledJob(&created)
+	s.scheduler.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.scheduler.notify()
+
+	return &created, nil
+}
+
+func (s *AgentService) UpdateScheduledJob(job *models.ScheduledJob) (*models.ScheduledJob, error) {
+	if job == nil {
+		return nil, fmt.Errorf("scheduled job is required")
+	}
+
+	s.scheduler.mu.Lock()
+	defer s.scheduler.mu.Unlock()
+
+	current, err := storage.LoadScheduledJob(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("scheduled job not found: %s", job.ID)
+	}
+
+	updated := *current
+	updated.Name = job.Name
+	updated.Prompt = job.Prompt
+	updated.ThreadID = job.ThreadID
+	updated.Kind = job.Kind
+	updated.RunAt = job.RunAt
+	updated.Cron = job.Cron
+	updated.MissedRunPolicy = job.MissedRunPolicy
+	updated.Enabled = job.Enabled
+	if err := normalizeScheduledJob(&updated); err != nil {
+		return nil, err
+	}
+	if err := s.validateScheduledJobTarget(&updated); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updated.NextRunAt = nextRunAt(&updated, now)
+	updated.UpdatedAt = now.UnixMilli()
+	if err := storage.SaveScheduledJob(&updated); err != nil {
+		return nil, err
+	}
+
+	s.scheduler.notify()
+
+	return &updated, nil
+}
+
+func (s *AgentService) DeleteScheduledJob(id string) error {
+	s.scheduler.mu.Lock()
+	defer s.scheduler.mu.Unlock()
+
+	job, err := storage.LoadScheduledJob(id)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("scheduled job not found: %s", id)
+	}
+
+	return storage.DeleteScheduledJob(id)
+}
+
+func (s *AgentService) validateScheduledJobTarget(job *models.ScheduledJob) error {
+	if job.ThreadID != "" {
+		s.mu.RLock()
+		_, exists := s.agents[job.ThreadID]
+		s.mu.RUnlock()
+
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrThreadNotFound, job.ThreadID)
+		}
+	}
+
+	if job.Enabled && job.Kind == models.ScheduleKindOnce && time.Since(time.UnixMilli(job.RunAt)) > missedRunGrace {
+		return fmt.Errorf("one-shot job run time is in the past")
+	}
+
+	return nil
+}
+
+func (s *AgentService) OnScheduledRun(handler func(job *models.ScheduledJob, threadID string, msgChan <-chan models.AgentMessage)) {
+	s.scheduler.mu.Lock()
+	s.scheduler.runHandler = handler
+	s.scheduler.mu.Unlock()
+}
+
+// StartScheduler first handles runs missed while the app was closed, then
+// checks for due jobs on every tick or whenever a job changes.
+func (s *AgentService) StartScheduler(ctx context.Context) {
+	s.scheduler.mu.Lock()
+	if s.scheduler.started {
+		s.scheduler.mu.Unlock()
+		return
+	}
+	s.scheduler.started = true
+	s.scheduler.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(schedulerTickInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.runDueJobs(ctx, time.Now()); err != nil {
+				fmt.Printf("Failed to run scheduled jobs: %v\n", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.scheduler.wake:
+			}
+		}
+	}()
+}
+
+func (s *AgentService) runDueJobs(ctx context.Context, now time.Time) error {
+	s.scheduler.mu.Lock()
+	defer s.scheduler.mu.Unlock()
+
+	jobs, err := storage.LoadScheduledJobs()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if !job.Enabled || job.NextRunAt == 0 || job.NextRunAt > now.UnixMilli() {
+			continue
+		}
+		if s.scheduler.running[job.ID] {
+			continue
+		}
+
+		dueAt := time.UnixMilli(job.NextRunAt)
+		missed := now.Sub(dueAt) > missedRunGrace
+
+		if job.Kind == models.ScheduleKindOnce {
+			job.Enabled = false
+		}
+		job.NextRunAt = nextRunAt(job, now)
+		job.UpdatedAt = now.UnixMilli()
+
+		if missed && job.MissedRunPolicy == models.MissedRunPolicySkip {
+			job.LastError = fmt.Sprintf("skipped run missed at %s", dueAt.Format(time.RFC3339))
+			if err := storage.SaveScheduledJob(job); err != nil {
+				return err
+			}
+			continue
+		}
+
+		job.LastRunAt = now.UnixMilli()
+		job.LastError = ""
+		if err := storage.SaveScheduledJob(job); err != nil {
+			return err
+		}
+
+		s.scheduler.running[job.ID] = true
+		go s.runScheduledJob(ctx, job, s.scheduler.runHandler)
+	}
+
+	return nil
+}
+
+func (s *AgentService) runScheduledJob(ctx context.Context, job *models.ScheduledJob, handler scheduledRunHandler) {
+	threadID := job.ThreadID
+	runErr := func() string {
+		if threadID == "" {
+			var err error
+			threadID, err = s.CreateThread(ctx)
+			if err != nil {
+				return fmt.Sprintf("failed to create thread: %v", err)
+			}
+			if job.Name != "" {
+				if err := s.UpdateThreadTitle(threadID, job.Name); err != nil {
+					fmt.Printf("Failed to title scheduled thread %s: %v\n", threadID, err)
+				}
+			}
+		}
+
+		msgChan, err := s.StreamRequestToThread(ctx, threadID, job.Prompt, nil)
+		if err != nil {
+			return fmt.Sprintf("failed to start run: %v", err)
+		}
+
+		outChan := make(chan models.AgentMessage)
+		if handler != nil {
+			go handler(job, threadID, outChan)
+		} else {
+			go func() {
+				for range outChan {
+				}
+			}()
+		}
+
+		var lastErr string
+		for msg := range msgChan {
+			switch m := msg.(type) {
+			case models.AgentError:
+				lastErr = m.Error
+			case models.AgentBudgetExceeded:
+				lastErr = fmt.Sprintf("%s budget exceeded", m.Scope)
+			case models.AgentFinalResponse:
+				lastErr = ""
+			}
+			outChan <- msg
+		}
+		close(outChan)
+
+		return lastErr
+	}()
+
+	s.scheduler.mu.Lock()
+	defer s.scheduler.mu.Unlock()
+
+	delete(s.scheduler.running, job.ID)
+
+	current, err := storage.LoadScheduledJob(job.ID)
+	if err != nil || current == nil {
+		return
+	}
+	current.LastThreadID = threadID
+	current.LastError = runErr
+	if err := storage.SaveScheduledJob(current); err != nil {
+		fmt.Printf("Failed to save scheduled job %s: %v\n", job.ID, err)
+	}
+}
@@ -0,0 +1,226 @@
+package scripted
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const streamChunkRunes = 16
+
+type ChatModel struct {
+	mu       sync.Mutex
+	script   *Script
+	next     int
+	requests [][]*schema.Message
+	tools    []*schema.ToolInfo
+}
+
+func NewChatModel(script *Script) *ChatModel {
+	if script == nil {
+		script = &Script{}
+	}
+
+	return &ChatModel{
+		script: script,
+	}
+}
+
+func (m *ChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	turn, err := m.nextTurn(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildMessage(turn), nil
+}
+
+func (m *ChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	turn, err := m.nextTurn(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.StreamReaderFromArray(buildChunks(turn)), nil
+}
+
+func (m *ChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tools = tools
+	return m, nil
+}
+
+func (m *ChatModel) Requests() [][]*schema.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := make([][]*schema.Message, len(m.requests))
+	copy(requests, m.requests)
+	return requests
+}
+
+func (m *ChatModel) Tools() []*schema.ToolInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.tools
+}
+
+func (m *ChatModel) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.script.Turns) - m.next
+}
+
+func (m *ChatModel) nextTurn(ctx context.Context, input []*schema.Message) (*Turn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, append([]*schema.Message(nil), input...))
+
+	if m.next >= len(m.script.Turns) {
+		return nil, fmt.Errorf("scripted model has no turn left after %d turns", len(m.script.Turns))
+	}
+
+	turn := m.script.Turns[m.next]
+	m.next += 1
+
+	if turn.Error != "" {
+		return nil, errors.New(turn.Error)
+	}
+
+	return turn, nil
+}
+
+func buildMessage(turn *Turn) *schema.Message {
+	msg := &schema.Message{
+		Role:             schema.Assistant,
+		Content:          turn.Content,
+		ReasoningContent: turn.Reasoning,
+		ResponseMeta:     buildResponseMeta(turn),
+	}
+
+	for i, tc := range turn.ToolCalls {
+		index := i
+		msg.ToolCalls = append(msg.ToolCalls, schema.ToolCall{
+			Index: &index,
+			ID:    toolCallID(tc, i),
+			Type:  "function",
+			Function: schema.FunctionCall{
+				Name:      tc.Name,
+				Arguments: toolCallArguments(tc),
+			},
+		})
+	}
+
+	return msg
+}
+
+func buildChunks(turn *Turn) []*schema.Message {
+	chunks := make([]*schema.Message, 0)
+
+	for _, part := range splitRunes(turn.Reasoning, streamChunkRunes) {
+		chunks = append(chunks, &schema.Message{
+			Role:             schema.Assistant,
+			ReasoningContent: part,
+		})
+	}
+
+	for _, part := range splitRunes(turn.Content, streamChunkRunes) {
+		chunks = append(chunks, &schema.Message{
+			Role:    schema.Assistant,
+			Content: part,
+		})
+	}
+
+	for i, tc := range turn.ToolCalls {
+		args := splitRunes(toolCallArguments(tc), streamChunkRunes)
+		for j, part := range args {
+			index := i
+			call := schema.ToolCall{
+				Index: &index,
+				Function: schema.FunctionCall{
+					Arguments: part,
+				},
+			}
+			if j == 0 {
+				call.ID = toolCallID(tc, i)
+				call.Type = "function"
+				call.Function.Name = tc.Name
+			}
+			chunks = append(chunks, &schema.Message{
+				Role:      schema.Assistant,
+				ToolCalls: []schema.ToolCall{call},
+			})
+		}
+	}
+
+	chunks = append(chunks, &schema.Message{
+		Role:         schema.Assistant,
+		ResponseMeta: buildResponseMeta(turn),
+	})
+
+	return chunks
+}
+
+func buildResponseMeta(turn *Turn) *schema.ResponseMeta {
+	meta := &schema.ResponseMeta{
+		FinishReason: "stop",
+	}
+	if len(turn.ToolCalls) > 0 {
+		meta.FinishReason = "tool_calls"
+	}
+	if turn.Usage != nil {
+		meta.Usage = &schema.TokenUsage{
+			PromptTokens: turn.Usage.PromptTokens,
+			PromptTokenDetails: schema.PromptTokenDetails{
+				CachedTokens: turn.Usage.CachedPromptTokens,
+			},
+			CompletionTokens: turn.Usage.CompletionTokens,
+			TotalTokens:      turn.Usage.PromptTokens + turn.Usage.CompletionTokens,
+		}
+	}
+
+	return meta
+}
+
+func toolCallID(tc *ToolCall, index int) string {
+	if tc.ID != "" {
+		return tc.ID
+	}
+
+	return fmt.Sprintf("scripted_call_%d", index)
+}
+
+func toolCallArguments(tc *ToolCall) string {
+	if tc.Arguments == "" {
+		return "{}"
+	}
+
+	return tc.Arguments
+}
+
+func splitRunes(text string, size int) []string {
+	if text == "" {
+		return nil
+	}
+
+	runes := []rune(text)
+	parts := make([]string, 0, (len(runes)+size-1)/size)
+	for start := 0; start < len(runes); start += size {
+		parts = append(parts, string(runes[start:min(start+size, len(runes))]))
+	}
+
+	return parts
+}
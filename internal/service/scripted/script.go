@@ -0,0 +1,66 @@
+package scripted
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Script struct {
+	Turns []*Turn `json:"turns" yaml:"turns"`
+}
+
+type Turn struct {
+	Content   string      `json:"content,omitempty" yaml:"content,omitempty"`
+	Reasoning string      `json:"reasoning,omitempty" yaml:"reasoning,omitempty"`
+	ToolCalls []*ToolCall `json:"tool_calls,omitempty" yaml:"tool_calls,omitempty"`
+	Usage     *Usage      `json:"usage,omitempty" yaml:"usage,omitempty"`
+	Error     string      `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type ToolCall struct {
+	ID        string `json:"id,omitempty" yaml:"id,omitempty"`
+	Name      string `json:"name" yaml:"name"`
+	Arguments string `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens       int `json:"prompt_tokens" yaml:"prompt_tokens"`
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty" yaml:"cached_prompt_tokens,omitempty"`
+	CompletionTokens   int `json:"completion_tokens" yaml:"completion_tokens"`
+}
+
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script %s: %w", path, err)
+	}
+
+	script, err := ParseScript(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script %s: %w", path, err)
+	}
+
+	return script, nil
+}
+
+func ParseScript(data []byte) (*Script, error) {
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, err
+	}
+
+	for i, turn := range script.Turns {
+		if turn == nil {
+			return nil, fmt.Errorf("turn %d is empty", i)
+		}
+		for j, tc := range turn.ToolCalls {
+			if tc == nil || tc.Name == "" {
+				return nil, fmt.Errorf("turn %d tool call %d has no name", i, j)
+			}
+		}
+	}
+
+	return &script, nil
+}
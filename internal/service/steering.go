@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+const (
+	steeringExtraKey     = "alter_steering"
+	steeringTextExtraKey = "alter_steering_text"
+)
+
+type steeringNote struct {
+	text      string
+	timestamp int64
+}
+
+func (a *Agent) Steer(text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("steering text is empty")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancelFunc == nil || !a.acceptingSteering {
+		return ErrThreadNotRunning
+	}
+
+	a.pendingSteering = append(a.pendingSteering, steeringNote{
+		text:      text,
+		timestamp: time.Now().UnixMilli(),
+	})
+	return nil
+}
+
+func (a *Agent) openSteering() {
+	a.mu.Lock()
+	a.pendingSteering = nil
+	a.acceptingSteering = true
+	a.mu.Unlock()
+}
+
+func (a *Agent) closeSteeringIfIdle() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.pendingSteering) > 0 {
+		return false
+	}
+
+	a.acceptingSteering = false
+	return true
+}
+
+func (a *Agent) injectSteering(msgChan chan<- models.AgentMessage) {
+	a.mu.Lock()
+	notes := a.pendingSteering
+	a.pendingSteering = nil
+	a.mu.Unlock()
+
+	for _, note := range notes {
+		a.appendMessageAt(&schema.Message{
+			Role:    schema.User,
+			Content: formatSteeringContent(note.text),
+			Extra: map[string]any{
+				steeringExtraKey:     true,
+				steeringTextExtraKey: note.text,
+			},
+		}, note.timestamp)
+
+		msgChan <- models.AgentSteered{
+			Content:   note.text,
+			Timestamp: note.timestamp,
+		}
+	}
+}
+
+func formatSteeringContent(text string) string {
+	return "[The user sent this message while you were still working on the current task. Take it into account before continuing.]\n" + text
+}
+
+func isSteeringMessage(msg *schema.Message) bool {
+	steering, _ := msg.Extra[steeringExtraKey].(bool)
+	return steering
+}
+
+func isInjectedMessage(msg *schema.Message) bool {
+	return isSteeringMessage(msg) || messageHookEvent(msg) != ""
+}
+
+func displayContent(msg *schema.Message) string {
+	if text, ok := msg.Extra[steeringTextExtraKey].(string); ok {
+		return text
+	}
+
+	return msg.Content
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/zjregee/alter/internal/utils"
+)
+
+const (
+	artifactsDir       = "artifacts"
+	artifactFileSuffix = ".txt"
+)
+
+var artifactIDPattern = regexp.MustCompile(`^artifact-[0-9a-f]{8}$`)
+
+func SaveArtifact(threadID string, content []byte) (string, error) {
+	if threadID == "" || filepath.Base(threadID) != threadID {
+		return "", fmt.Errorf("invalid thread id: %s", threadID)
+	}
+
+	dir, err := threadArtifactsDir(threadID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+
+	id := fmt.Sprintf("artifact-%s", utils.GenerateFullUUID())
+	if err := os.WriteFile(filepath.Join(dir, id+artifactFileSuffix), content, 0600); err != nil {
+		return "", fmt.Errorf("failed to write artifact %s: %w", id, err)
+	}
+
+	return id, nil
+}
+
+func ReadArtifact(threadID string, id string) ([]byte, error) {
+	path, err := artifactPath(threadID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact %s: %w", id, err)
+	}
+
+	return content, nil
+}
+
+func DeleteThreadArtifacts(threadID string) error {
+	if threadID == "" || filepath.Base(threadID) != threadID {
+		return fmt.Errorf("invalid thread id: %s", threadID)
+	}
+
+	dir, err := threadArtifactsDir(threadID)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+func threadArtifactsDir(threadID string) (string, error) {
+	alterDir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(alterDir, artifactsDir, threadID), nil
+}
+
+func artifactPath(threadID string, id string) (string, error) {
+	if threadID == "" || filepath.Base(threadID) != threadID {
+		return "", fmt.Errorf("invalid thread id: %s", threadID)
+	}
+	if !artifactIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid artifact id: %s", id)
+	}
+
+	dir, err := threadArtifactsDir(threadID)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, id+artifactFileSuffix)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("artifact not found: %s", id)
+		}
+		return "", fmt.Errorf("failed to stat artifact %s: %w", id, err)
+	}
+
+	return path, nil
+}
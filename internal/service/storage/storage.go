@@ -15,6 +15,7 @@ const (
 	defaultDir      = ".alter"
 	defaultBucket   = "alter"
 	defaultFileName = "alter.db"
+	dataDirEnv      = "ALTER_HOME"
 )
 
 type database struct {
@@ -83,15 +84,27 @@ func List(prefix []byte) (map[string][]byte, error) {
 	return instance.list(prefix)
 }
 
-func newDatabase() (*database, bool, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, false, fmt.Errorf("failed to get home directory: %w", err)
+func dataDir() (string, error) {
+	alterDir := os.Getenv(dataDirEnv)
+	if alterDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		alterDir = filepath.Join(homeDir, defaultDir)
 	}
 
-	alterDir := filepath.Join(homeDir, defaultDir)
 	if err := os.MkdirAll(alterDir, 0755); err != nil {
-		return nil, false, fmt.Errorf("failed to create .alter directory: %w", err)
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	return alterDir, nil
+}
+
+func newDatabase() (*database, bool, error) {
+	alterDir, err := dataDir()
+	if err != nil {
+		return nil, false, err
 	}
 
 	dbPath := filepath.Join(alterDir, defaultFileName)
@@ -7,44 +7,70 @@ import (
 	"github.com/cloudwego/eino/schema"
 
 	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/utils"
 )
 
 const (
-	threadKeyPrefix   = "thread:"
-	workspaceInfosKey = "workspace:infos"
+	threadKeyPrefix               = "thread:"
+	workspaceInfosKey             = "workspace:infos"
+	toolApprovalSettingsKeyPrefix = "workspace:tool_approval:"
+	workspaceHooksKeyPrefix       = "workspace:hooks:"
+	bashPolicyKeyPrefix           = "workspace:bash_policy:"
+	attachmentKeyPrefix           = "attachment:"
+	budgetSettingsKey             = "budget:settings"
+	dailyUsageKeyPrefix           = "usage:daily:"
+	scheduledJobKeyPrefix         = "schedule:job:"
+	apiTokenKey                   = "api:token"
+	mcpServersKey                 = "mcp:servers"
+	workspaceMCPServersKeyPrefix  = "workspace:mcp:"
 )
 
 const defaultWorkspacePath = "/Users/zjregee/Code/alter"
 
+const threadRecordVersion = 2
+
 type ThreadRecord struct {
-	Info              *models.ThreadInfo `json:"info"`
-	Messages          []*schema.Message  `json:"messages"`
-	MessageTimestamps []int64            `json:"message_timestamps"`
-	Stats             *models.AgentStats `json:"stats"`
+	Version           int                      `json:"version,omitempty"`
+	Info              *models.ThreadInfo       `json:"info"`
+	Nodes             []*models.MessageNode    `json:"nodes,omitempty"`
+	ActiveLeafID      string                   `json:"active_leaf_id,omitempty"`
+	Messages          []*schema.Message        `json:"messages,omitempty"`
+	MessageTimestamps []int64                  `json:"message_timestamps,omitempty"`
+	Stats             *models.AgentStats       `json:"stats"`
+	Compaction        *models.ThreadCompaction `json:"compaction,omitempty"`
+}
+
+type AttachmentRecord struct {
+	Info *models.Attachment `json:"info"`
+	Data []byte             `json:"data"`
 }
 
 type WorkspaceInfosRecord struct {
 	Infos []*models.WorkspaceInfo `json:"infos"`
 }
 
-func SaveThread(info *models.ThreadInfo, messages []*schema.Message, messageTimestamps []int64, stats *models.AgentStats) error {
+func SaveThread(info *models.ThreadInfo, nodes []*models.MessageNode, activeLeafID string, stats *models.AgentStats, compaction *models.ThreadCompaction) error {
 	if info == nil {
 		return fmt.Errorf("thread info is required")
 	}
 
-	payload := ThreadRecord{
-		Info:              info,
-		Messages:          messages,
-		MessageTimestamps: messageTimestamps,
-		Stats:             stats,
-	}
+	return saveThreadRecord(&ThreadRecord{
+		Version:      threadRecordVersion,
+		Info:         info,
+		Nodes:        nodes,
+		ActiveLeafID: activeLeafID,
+		Stats:        stats,
+		Compaction:   compaction,
+	})
+}
 
-	data, err := json.Marshal(payload)
+func saveThreadRecord(record *ThreadRecord) error {
+	data, err := json.Marshal(record)
 	if err != nil {
-		return fmt.Errorf("failed to marshal thread %s: %w", info.ID, err)
+		return fmt.Errorf("failed to marshal thread %s: %w", record.Info.ID, err)
 	}
 
-	return Put([]byte(threadKeyPrefix+info.ID), data)
+	return Put([]byte(threadKeyPrefix+record.Info.ID), data)
 }
 
 func LoadThreads() ([]*ThreadRecord, error) {
@@ -68,12 +94,49 @@ func LoadThreads() ([]*ThreadRecord, error) {
 			continue
 		}
 
+		if stored.Version < threadRecordVersion {
+			if err := migrateThreadRecord(&stored); err != nil {
+				return nil, err
+			}
+		}
+
 		threads = append(threads, &stored)
 	}
 
 	return threads, nil
 }
 
+func migrateThreadRecord(record *ThreadRecord) error {
+	if len(record.Messages) != len(record.MessageTimestamps) {
+		return fmt.Errorf("failed to migrate thread %s: messages and timestamps mismatch", record.Info.ID)
+	}
+
+	nodes := make([]*models.MessageNode, 0, len(record.Messages))
+	parentID := ""
+	for i, msg := range record.Messages {
+		node := &models.MessageNode{
+			ID:        fmt.Sprintf("msg-%s", utils.GenerateFullUUID()),
+			ParentID:  parentID,
+			Message:   msg,
+			Timestamp: record.MessageTimestamps[i],
+		}
+		nodes = append(nodes, node)
+		parentID = node.ID
+	}
+
+	record.Version = threadRecordVersion
+	record.Nodes = nodes
+	record.ActiveLeafID = parentID
+	record.Messages = nil
+	record.MessageTimestamps = nil
+
+	if err := saveThreadRecord(record); err != nil {
+		return fmt.Errorf("failed to migrate thread %s: %w", record.Info.ID, err)
+	}
+
+	return nil
+}
+
 func DeleteThread(id string) error {
 	if id == "" {
 		return fmt.Errorf("thread id is required")
@@ -82,6 +145,63 @@ func DeleteThread(id string) error {
 	return Delete([]byte(threadKeyPrefix + id))
 }
 
+func SaveAttachment(threadID string, info *models.Attachment, data []byte) error {
+	if threadID == "" || info == nil || info.ID == "" {
+		return fmt.Errorf("attachment thread id and id are required")
+	}
+
+	payload, err := json.Marshal(AttachmentRecord{
+		Info: info,
+		Data: data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal attachment %s: %w", info.ID, err)
+	}
+
+	return Put([]byte(attachmentKey(threadID, info.ID)), payload)
+}
+
+func LoadAttachment(threadID string, id string) (*AttachmentRecord, error) {
+	value, err := Get([]byte(attachmentKey(threadID, id)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, fmt.Errorf("attachment not found: %s", id)
+	}
+
+	var record AttachmentRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attachment %s: %w", id, err)
+	}
+
+	return &record, nil
+}
+
+func DeleteThreadAttachments(threadID string) error {
+	if threadID == "" {
+		return fmt.Errorf("thread id is required")
+	}
+
+	entries, err := List([]byte(attachmentKeyPrefix + threadID + ":"))
+	if err != nil {
+		return err
+	}
+
+	for key := range entries {
+		if err := Delete([]byte(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func attachmentKey(threadID string, id string) string {
+	return attachmentKeyPrefix + threadID + ":" + id
+}
+
 func SaveWorkspaceInfos(infos []*models.WorkspaceInfo) error {
 	record := WorkspaceInfosRecord{
 		Infos: infos,
@@ -115,6 +235,274 @@ func LoadWorkspaceInfos() (*WorkspaceInfosRecord, error) {
 	return &record, nil
 }
 
+func SaveToolApprovalSettings(settings *models.ToolApprovalSettings) error {
+	if settings == nil || settings.WorkspacePath == "" {
+		return fmt.Errorf("tool approval settings workspace path is required")
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tool approval settings: %w", err)
+	}
+
+	return Put([]byte(toolApprovalSettingsKeyPrefix+settings.WorkspacePath), data)
+}
+
+func LoadToolApprovalSettings(workspacePath string) (*models.ToolApprovalSettings, error) {
+	value, err := Get([]byte(toolApprovalSettingsKeyPrefix + workspacePath))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var settings models.ToolApprovalSettings
+	if err := json.Unmarshal(value, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tool approval settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func DeleteToolApprovalSettings(workspacePath string) error {
+	return Delete([]byte(toolApprovalSettingsKeyPrefix + workspacePath))
+}
+
+func SaveWorkspaceHooks(hooks *models.WorkspaceHooks) error {
+	if hooks == nil || hooks.WorkspacePath == "" {
+		return fmt.Errorf("workspace hooks workspace path is required")
+	}
+
+	data, err := json.Marshal(hooks)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace hooks: %w", err)
+	}
+
+	return Put([]byte(workspaceHooksKeyPrefix+hooks.WorkspacePath), data)
+}
+
+func LoadWorkspaceHooks(workspacePath string) (*models.WorkspaceHooks, error) {
+	value, err := Get([]byte(workspaceHooksKeyPrefix + workspacePath))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var hooks models.WorkspaceHooks
+	if err := json.Unmarshal(value, &hooks); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workspace hooks: %w", err)
+	}
+
+	return &hooks, nil
+}
+
+func DeleteWorkspaceHooks(workspacePath string) error {
+	return Delete([]byte(workspaceHooksKeyPrefix + workspacePath))
+}
+
+func SaveBashPolicy(policy *models.BashPolicy) error {
+	if policy == nil || policy.WorkspacePath == "" {
+		return fmt.Errorf("bash policy workspace path is required")
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bash policy: %w", err)
+	}
+
+	return Put([]byte(bashPolicyKeyPrefix+policy.WorkspacePath), data)
+}
+
+func LoadBashPolicy(workspacePath string) (*models.BashPolicy, error) {
+	value, err := Get([]byte(bashPolicyKeyPrefix + workspacePath))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var policy models.BashPolicy
+	if err := json.Unmarshal(value, &policy); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bash policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+func DeleteBashPolicy(workspacePath string) error {
+	return Delete([]byte(bashPolicyKeyPrefix + workspacePath))
+}
+
+func SaveScheduledJob(job *models.ScheduledJob) error {
+	if job == nil || job.ID == "" {
+		return fmt.Errorf("scheduled job ID is required")
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scheduled job: %w", err)
+	}
+
+	return Put([]byte(scheduledJobKeyPrefix+job.ID), data)
+}
+
+func LoadScheduledJobs() ([]*models.ScheduledJob, error) {
+	entries, err := List([]byte(scheduledJobKeyPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*models.ScheduledJob, 0, len(entries))
+	for key, value := range entries {
+		var job models.ScheduledJob
+		if err := json.Unmarshal(value, &job); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal scheduled job %s: %w", key, err)
+		}
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, nil
+}
+
+func LoadScheduledJob(id string) (*models.ScheduledJob, error) {
+	value, err := Get([]byte(scheduledJobKeyPrefix + id))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var job models.ScheduledJob
+	if err := json.Unmarshal(value, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scheduled job %s: %w", id, err)
+	}
+
+	return &job, nil
+}
+
+func DeleteScheduledJob(id string) error {
+	return Delete([]byte(scheduledJobKeyPrefix + id))
+}
+
+func SaveAPIToken(token string) error {
+	return Put([]byte(apiTokenKey), []byte(token))
+}
+
+func LoadAPIToken() (string, error) {
+	value, err := Get([]byte(apiTokenKey))
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
+
+func SaveBudgetSettings(settings *models.BudgetSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal budget settings: %w", err)
+	}
+
+	return Put([]byte(budgetSettingsKey), data)
+}
+
+func LoadBudgetSettings() (*models.BudgetSettings, error) {
+	value, err := Get([]byte(budgetSettingsKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var settings models.BudgetSettings
+	if err := json.Unmarshal(value, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal budget settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func SaveDailyUsage(day string, usage *models.AgentUsage) error {
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return fmt.Errorf("failed to marshal daily usage: %w", err)
+	}
+
+	return Put([]byte(dailyUsageKeyPrefix+day), data)
+}
+
+func LoadDailyUsage(day string) (*models.AgentUsage, error) {
+	value, err := Get([]byte(dailyUsageKeyPrefix + day))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var usage models.AgentUsage
+	if err := json.Unmarshal(value, &usage); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal daily usage: %w", err)
+	}
+
+	return &usage, nil
+}
+
+func mcpServerSettingsKey(workspacePath string) []byte {
+	if workspacePath == "" {
+		return []byte(mcpServersKey)
+	}
+
+	return []byte(workspaceMCPServersKeyPrefix + workspacePath)
+}
+
+func SaveMCPServerSettings(settings *models.MCPServerSettings) error {
+	if settings == nil {
+		return fmt.Errorf("MCP server settings are required")
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal MCP server settings: %w", err)
+	}
+
+	return Put(mcpServerSettingsKey(settings.WorkspacePath), data)
+}
+
+func LoadMCPServerSettings(workspacePath string) (*models.MCPServerSettings, error) {
+	value, err := Get(mcpServerSettingsKey(workspacePath))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var settings models.MCPServerSettings
+	if err := json.Unmarshal(value, &settings); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal MCP server settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func DeleteMCPServerSettings(workspacePath string) error {
+	return Delete(mcpServerSettingsKey(workspacePath))
+}
+
 func initWorkspaceInfos() {
 	infos := []*models.WorkspaceInfo{
 		{
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+func receiveStream(stream *schema.StreamReader[*schema.Message], msgChan chan<- models.AgentMessage) (message *schema.Message, err error) {
+	defer stream.Close()
+
+	// Whoever called for the response retries it, falls back to another model or
+	// reports the error, so clients must not keep the deltas of a failed one.
+	streamed := false
+	defer func() {
+		if err != nil && streamed {
+			msgChan <- models.AgentStreamDiscarded{}
+		}
+	}()
+
+	var chunks []*schema.Message
+	var toolCallChunks []schema.ToolCall
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk == nil {
+			continue
+		}
+
+		if chunk.ReasoningContent != "" {
+			msgChan <- models.AgentReasoningDelta{Content: chunk.ReasoningContent}
+			streamed = true
+		}
+		if chunk.Content != "" {
+			msgChan <- models.AgentThoughtDelta{Content: chunk.Content}
+			streamed = true
+		}
+
+		if len(chunk.ToolCalls) > 0 {
+			toolCallChunks = append(toolCallChunks, chunk.ToolCalls...)
+			stripped := *chunk
+			stripped.ToolCalls = nil
+			chunk = &stripped
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("model returned an empty stream")
+	}
+
+	message, err = schema.ConcatMessages(chunks)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role == "" {
+		message.Role = schema.Assistant
+	}
+
+	toolCalls, err := mergeToolCallChunks(toolCallChunks)
+	if err != nil {
+		return nil, err
+	}
+	message.ToolCalls = toolCalls
+
+	return message, nil
+}
+
+func mergeToolCallChunks(chunks []schema.ToolCall) ([]schema.ToolCall, error) {
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	type pending struct {
+		call schema.ToolCall
+		args strings.Builder
+	}
+
+	var merged []*pending
+	byIndex := make(map[int]*pending)
+	byID := make(map[string]*pending)
+
+	var last *pending
+	for _, chunk := range chunks {
+		var current *pending
+		switch {
+		case chunk.Index != nil:
+			current = byIndex[*chunk.Index]
+		case chunk.ID != "":
+			current = byID[chunk.ID]
+		default:
+			current = last
+		}
+
+		if current != nil && chunk.ID != "" && current.call.ID != "" && current.call.ID != chunk.ID {
+			if chunk.Index != nil {
+				return nil, fmt.Errorf("tool call chunks at index %d have different ids: %s, %s", *chunk.Index, current.call.ID, chunk.ID)
+			}
+			current = nil
+		}
+
+		if current == nil {
+			current = &pending{}
+			if chunk.Index != nil {
+				index := *chunk.Index
+				current.call.Index = &index
+				byIndex[index] = current
+			}
+			merged = append(merged, current)
+		}
+
+		if chunk.ID != "" {
+			current.call.ID = chunk.ID
+			byID[chunk.ID] = current
+		}
+		if chunk.Type != "" {
+			current.call.Type = chunk.Type
+		}
+		if chunk.Function.Name != "" {
+			current.call.Function.Name = chunk.Function.Name
+		}
+		if len(chunk.Extra) > 0 {
+			if current.call.Extra == nil {
+				current.call.Extra = make(map[string]any, len(chunk.Extra))
+			}
+			for k, v := range chunk.Extra {
+				current.call.Extra[k] = v
+			}
+		}
+		current.args.WriteString(chunk.Function.Arguments)
+
+		last = current
+	}
+
+	toolCalls := make([]schema.ToolCall, 0, len(merged))
+	for i, p := range merged {
+		call := p.call
+		call.Function.Arguments = p.args.String()
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_%d", i)
+		}
+		if call.Function.Name == "" {
+			return nil, fmt.Errorf("tool call %s has no function name", call.ID)
+		}
+		if call.Type == "" {
+			call.Type = "function"
+		}
+		if strings.TrimSpace(call.Function.Arguments) == "" {
+			call.Function.Arguments = "{}"
+		}
+		toolCalls = append(toolCalls, call)
+	}
+
+	return toolCalls, nil
+}
@@ -0,0 +1,103 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zjregee/alter/internal/service/storage"
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+const (
+	defaultPageLines = 200
+	maxPageBytes     = 16 * 1024
+)
+
+func ReadArtifact(ctx context.Context, params *ReadArtifactParams) (string, error) {
+	if params == nil {
+		return "", fmt.Errorf("params must be provided")
+	}
+
+	artifactID := strings.TrimSpace(params.ArtifactID)
+	if artifactID == "" {
+		return "", fmt.Errorf("artifact_id must be provided")
+	}
+
+	threadID := tools.ThreadIDFromContext(ctx)
+	if threadID == "" {
+		return "", fmt.Errorf("artifacts can only be read from a thread")
+	}
+
+	content, err := storage.ReadArtifact(threadID, artifactID)
+	if err != nil {
+		return "", err
+	}
+
+	if params.Length > 0 {
+		return readByteRange(artifactID, content, params.Offset, params.Length)
+	}
+
+	return readLineRange(artifactID, content, params.StartLine, params.EndLine)
+}
+
+func readByteRange(artifactID string, content []byte, offset int, length int) (string, error) {
+	if offset < 0 || offset >= len(content) {
+		return "", fmt.Errorf("offset out of range: %d (artifact size %d bytes)", offset, len(content))
+	}
+
+	end := min(offset+min(length, maxPageBytes), len(content))
+	page := content[offset:end]
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Artifact: %s\n", artifactID)
+	fmt.Fprintf(&b, "Bytes: %d-%d of %d\n", offset, end, len(content))
+	fmt.Fprint(&b, "Content:\n```text\n")
+	fmt.Fprint(&b, strings.ToValidUTF8(string(page), string(utf8.RuneError)))
+	fmt.Fprint(&b, "\n```")
+
+	return b.String(), nil
+}
+
+func readLineRange(artifactID string, content []byte, startLine int, endLine int) (string, error) {
+	lines := bytes.Split(bytes.TrimRight(content, "\n"), []byte("\n"))
+	totalLines := len(lines)
+
+	if startLine <= 0 {
+		startLine = 1
+	}
+	if startLine > totalLines {
+		return "", fmt.Errorf("start_line out of range: %d (artifact has %d lines)", startLine, totalLines)
+	}
+	if endLine <= 0 || endLine < startLine {
+		endLine = startLine + defaultPageLines - 1
+	}
+	endLine = min(endLine, totalLines)
+
+	var page strings.Builder
+	truncated := false
+	lastLine := startLine - 1
+	for i := startLine; i <= endLine; i += 1 {
+		line := fmt.Sprintf("%d\t%s\n", i, strings.ToValidUTF8(string(lines[i-1]), string(utf8.RuneError)))
+		if page.Len()+len(line) > maxPageBytes && page.Len() > 0 {
+			truncated = true
+			break
+		}
+		page.WriteString(line)
+		lastLine = i
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Artifact: %s\n", artifactID)
+	fmt.Fprintf(&b, "Lines: %d-%d of %d\n", startLine, lastLine, totalLines)
+	if truncated {
+		fmt.Fprintf(&b, "Note: page truncated at %d bytes, continue from line %d\n", maxPageBytes, lastLine+1)
+	}
+	fmt.Fprint(&b, "Content:\n```text\n")
+	fmt.Fprint(&b, strings.TrimRight(page.String(), "\n"))
+	fmt.Fprint(&b, "\n```")
+
+	return b.String(), nil
+}
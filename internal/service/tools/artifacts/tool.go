@@ -0,0 +1,42 @@
+package artifacts
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+const (
+	ReadArtifactToolName        = "read_artifact"
+	ReadArtifactToolDescription = "Reads a page of a large tool output that was saved as an artifact. Page by line range (start_line, end_line) or by byte range (offset, length). Defaults to the first 200 lines."
+)
+
+type ReadArtifactParams struct {
+	ArtifactID string `json:"artifact_id" jsonschema:"description=The artifact ID returned in place of a large tool output."`
+	StartLine  int    `json:"start_line,omitempty" jsonschema:"description=The first line to read, starting from 1."`
+	EndLine    int    `json:"end_line,omitempty" jsonschema:"description=The last line to read, inclusive."`
+	Offset     int    `json:"offset,omitempty" jsonschema:"description=The byte offset to start reading from. Used instead of lines when length is set."`
+	Length     int    `json:"length,omitempty" jsonschema:"description=The number of bytes to read from offset."`
+}
+
+func GetReadArtifactTool(ctx context.Context) (*schema.ToolInfo, tool.InvokableTool, error) {
+	t, err := utils.InferTool(ReadArtifactToolName, ReadArtifactToolDescription, ReadArtifact)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return info, t, nil
+}
+
+func init() {
+	tools.RegisterTool(ReadArtifactToolName, GetReadArtifactTool)
+}
@@ -1,59 +1,21 @@
 package bash
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/tools"
 )
 
 const defaultTimeoutSeconds = 10
 
-var allowedCommands = map[string]struct{}{
-	"ls":   {},
-	"tree": {},
-	"rg":   {},
-	"grep": {},
-	"cat":  {},
-	"head": {},
-	"tail": {},
-	"sed":  {},
-	"awk":  {},
-}
-
-func validateAllowedCommand(command string) error {
-	if containsShellOperator(command) {
-		return fmt.Errorf("command contains unsupported shell operators")
-	}
-
-	fields := strings.Fields(command)
-	if len(fields) == 0 {
-		return fmt.Errorf("command must be provided")
-	}
-
-	base := filepath.Base(fields[0])
-	if _, ok := allowedCommands[base]; !ok {
-		return fmt.Errorf("command is not allowed: %s", base)
-	}
-
-	return nil
-}
-
-func containsShellOperator(command string) bool {
-	if strings.ContainsAny(command, "|;&><`\n") {
-		return true
-	}
-	if strings.Contains(command, "$(") {
-		return true
-	}
-
-	return false
-}
-
 func BashTool(ctx context.Context, params *BashParams) (string, error) {
 	if params == nil {
 		return "", fmt.Errorf("params must be provided")
@@ -63,13 +25,26 @@ func BashTool(ctx context.Context, params *BashParams) (string, error) {
 	if command == "" {
 		return "", fmt.Errorf("command must be provided")
 	}
-	if err := validateAllowedCommand(command); err != nil {
+	decision, err := CheckCommand(PolicyFromContext(ctx), command)
+	if err != nil {
 		return "", err
 	}
+	switch decision.Action {
+	case models.BashPolicyDeny:
+		return "", fmt.Errorf("command is not allowed by the bash policy: %s", decision.Reason)
+	case models.BashPolicyAsk:
+		if !isApproved(ctx) {
+			return "", fmt.Errorf("command needs approval under the bash policy: %s", decision.Reason)
+		}
+	}
 
 	workDir := strings.TrimSpace(params.WorkDir)
-	if workDir != "" && !filepath.IsAbs(workDir) {
-		return "", fmt.Errorf("path must be absolute: %s", workDir)
+	if workDir == "" {
+		workDir = tools.WorkDirFromContext(ctx)
+	}
+	workDir, err = tools.ResolveWorkspacePath(ctx, workDir)
+	if err != nil {
+		return "", err
 	}
 
 	info, err := os.Stat(workDir)
@@ -91,10 +66,32 @@ func BashTool(ctx context.Context, params *BashParams) (string, error) {
 		defer cancel()
 	}
 
-	cmd := exec.CommandContext(ctx, "bash", "-lc", command)
+	// Login profiles tend to write under $HOME, which is read-only in the
+	// sandbox, so sandboxed commands run in a plain shell.
+	sandbox := PolicyFromContext(ctx).Sandbox
+	shellFlag := "-lc"
+	if sandbox {
+		shellFlag = "-c"
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "bash", shellFlag, command)
 	cmd.Dir = workDir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
 
-	output, err := cmd.CombinedOutput()
+	if sandbox {
+		var root string
+		if root, err = tools.ResolveWorkspacePath(ctx, tools.WorkDirFromContext(ctx)); err != nil {
+			return "", err
+		}
+		err = startSandboxed(cmd, root)
+	} else {
+		err = cmd.Start()
+	}
+	if err == nil {
+		err = cmd.Wait()
+	}
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
 			return "", fmt.Errorf("command timed out: %w", err)
@@ -102,13 +99,13 @@ func BashTool(ctx context.Context, params *BashParams) (string, error) {
 
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
-			return formatResult(command, workDir, exitErr.ExitCode(), output), nil
+			return formatResult(command, workDir, exitErr.ExitCode(), output.Bytes()), nil
 		}
 
 		return "", fmt.Errorf("command failed to run: %w", err)
 	}
 
-	return formatResult(command, workDir, 0, output), nil
+	return formatResult(command, workDir, 0, output.Bytes()), nil
 }
 
 func formatResult(command string, workDir string, exitCode int, output []byte) string {
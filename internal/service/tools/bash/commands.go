@@ -0,0 +1,297 @@
+package bash
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+// commandChecks look at the arguments of commands whose scripts, operands or
+// variable names can write files or run programs. They apply on top of the
+// policy rules, so a saved policy cannot allow these forms.
+var commandChecks = map[string]func(args []string) (models.BashPolicyAction, string){
+	"sed":    checkSed,
+	"awk":    checkAwk,
+	"uniq":   checkUniq,
+	"printf": checkPrintf,
+	"test":   checkTest,
+	"[":      checkTest,
+}
+
+var awkSystemCall = regexp.MustCompile(`\bsystem\s*\(`)
+
+func checkSed(args []string) (models.BashPolicyAction, string) {
+	var scripts, operands []string
+	hasScript := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg, "=")
+			switch {
+			case isLongOption(name, "--expression"):
+				if !hasValue && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+				scripts = append(scripts, value)
+				hasScript = true
+			case isLongOption(name, "--file"):
+				return models.BashPolicyAsk, "sed reads its script from a file"
+			case isLongOption(name, "--line-length") && !hasValue:
+				i++
+			}
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			for j := 1; j < len(arg); j++ {
+				flag := arg[j]
+				if flag != 'e' && flag != 'f' && flag != 'l' {
+					continue
+				}
+
+				value := arg[j+1:]
+				if value == "" && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+				if flag == 'f' {
+					return models.BashPolicyAsk, "sed reads its script from a file"
+				}
+				if flag == 'e' {
+					scripts = append(scripts, value)
+					hasScript = true
+				}
+				break
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	if !hasScript && len(operands) > 0 {
+		scripts = append(scripts, operands[0])
+	}
+	for _, script := range scripts {
+		command, err := sedUnsafeCommand(script)
+		if err != nil {
+			return models.BashPolicyAsk, fmt.Sprintf("sed script could not be checked: %v", err)
+		}
+		if command != "" {
+			return models.BashPolicyDeny, fmt.Sprintf("sed script uses the %s command", command)
+		}
+	}
+
+	return "", ""
+}
+
+// sedUnsafeCommand scans a sed script for the commands that run programs or
+// write files: e, w, W and the e and w flags of s.
+func sedUnsafeCommand(script string) (string, error) {
+	var err error
+	for i := 0; i < len(script); {
+		switch script[i] {
+		case ' ', '\t', '\n', ';', '{', '}':
+			i++
+			continue
+		case '#':
+			i = sedLineEnd(script, i)
+			continue
+		}
+
+		if i, err = sedSkipAddress(script, i); err != nil {
+			return "", err
+		}
+		if i >= len(script) {
+			return "", nil
+		}
+
+		command := script[i]
+		i++
+		switch command {
+		case '{', '}':
+		case 'e', 'w', 'W':
+			return string(command), nil
+		case 's':
+			if i, err = sedSkipDelimited(script, i, 2); err != nil {
+				return "", err
+			}
+			for ; i < len(script) && !strings.ContainsRune(";\n}", rune(script[i])); i++ {
+				if script[i] == 'e' || script[i] == 'w' {
+					return "s///" + string(script[i]), nil
+				}
+			}
+		case 'y':
+			if i, err = sedSkipDelimited(script, i, 2); err != nil {
+				return "", err
+			}
+		case 'a', 'i', 'c', 'r', 'R':
+			i = sedLineEnd(script, i)
+		case ':', 'b', 't', 'T', 'v', 'q', 'Q', 'l', 'L':
+			for i < len(script) && script[i] != ';' && script[i] != '\n' && script[i] != '}' {
+				i++
+			}
+		case '=', 'd', 'D', 'F', 'g', 'G', 'h', 'H', 'n', 'N', 'p', 'P', 'x', 'z':
+		default:
+			return "", fmt.Errorf("unknown command %q", command)
+		}
+	}
+
+	return "", nil
+}
+
+func sedSkipAddress(script string, i int) (int, error) {
+	for i < len(script) {
+		switch c := script[i]; {
+		case c >= '0' && c <= '9', strings.ContainsRune("$,~+! \t", rune(c)):
+			i++
+		case c == '/' || c == '\\':
+			delimStart := i + 1
+			if c == '\\' {
+				delimStart = i + 2
+				if i+1 >= len(script) {
+					return i, fmt.Errorf("unterminated address")
+				}
+			}
+			delim := script[delimStart-1]
+			end, err := sedDelimitedEnd(script, delimStart, delim)
+			if err != nil {
+				return i, err
+			}
+			i = end + 1
+			for i < len(script) && (script[i] == 'I' || script[i] == 'M') {
+				i++
+			}
+		default:
+			return i, nil
+		}
+	}
+
+	return i, nil
+}
+
+// sedSkipDelimited skips the parts of an s or y command, starting at its
+// delimiter, and returns the index after the last delimiter.
+func sedSkipDelimited(script string, i int, parts int) (int, error) {
+	if i >= len(script) || script[i] == '\n' || script[i] == '\\' {
+		return i, fmt.Errorf("missing delimiter")
+	}
+
+	delim := script[i]
+	i++
+	for range parts {
+		end, err := sedDelimitedEnd(script, i, delim)
+		if err != nil {
+			return i, err
+		}
+		i = end + 1
+	}
+
+	return i, nil
+}
+
+func sedDelimitedEnd(script string, i int, delim byte) (int, error) {
+	for ; i < len(script); i++ {
+		switch script[i] {
+		case '\\':
+			i++
+		case delim:
+			return i, nil
+		}
+	}
+
+	return i, fmt.Errorf("unterminated %q", delim)
+}
+
+func sedLineEnd(script string, i int) int {
+	if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(script)
+}
+
+// checkAwk denies calls to system wherever they appear, since the program may
+// put spaces or comments between the name and its arguments.
+func checkAwk(args []string) (models.BashPolicyAction, string) {
+	for _, arg := range args {
+		if awkSystemCall.MatchString(arg) {
+			return models.BashPolicyDeny, "awk program calls system"
+		}
+	}
+
+	return "", ""
+}
+
+// checkUniq denies a second operand, which uniq writes its output to.
+func checkUniq(args []string) (models.BashPolicyAction, string) {
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg, "=")
+			takesValue := isLongOption(name, "--skip-fields") ||
+				isLongOption(name, "--skip-chars") ||
+				isLongOption(name, "--check-chars")
+			if takesValue && !hasValue {
+				i++
+			}
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			for j := 1; j < len(arg); j++ {
+				if strings.IndexByte("fsw", arg[j]) >= 0 {
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	if len(operands) >= 2 {
+		return models.BashPolicyDeny, fmt.Sprintf("uniq writes its output to %s", operands[1])
+	}
+
+	return "", ""
+}
+
+// checkPrintf asks about -v, which assigns to a variable and runs any command
+// substitution in an array subscript of its name.
+func checkPrintf(args []string) (models.BashPolicyAction, string) {
+	for _, arg := range args {
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		if strings.Contains(arg, "v") {
+			return models.BashPolicyAsk, "printf -v assigns to a variable"
+		}
+	}
+
+	return "", ""
+}
+
+// checkTest asks about -v and -R, which look up a variable by name and run any
+// command substitution in its array subscript.
+func checkTest(args []string) (models.BashPolicyAction, string) {
+	for _, arg := range args {
+		if arg == "-v" || arg == "-R" {
+			return models.BashPolicyAsk, fmt.Sprintf("test %s looks up a variable by name", arg)
+		}
+	}
+
+	return "", ""
+}
+
+// isLongOption reports whether arg names option, allowing the unambiguous
+// abbreviations getopt_long accepts.
+func isLongOption(arg string, option string) bool {
+	return len(arg) > 2 && strings.HasPrefix(option, arg)
+}
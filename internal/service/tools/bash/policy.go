@@ -0,0 +1,446 @@
+package bash
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+type policyKey struct{}
+
+type approvalKey struct{}
+
+type Decision struct {
+	Action models.BashPolicyAction
+	Reason string
+}
+
+var readOnlyCommands = []string{
+	"ls", "tree", "rg", "grep", "cat", "head", "tail", "sed", "awk",
+	"wc", "sort", "uniq", "cut", "tr", "find", "diff", "stat", "file",
+	"du", "pwd", "echo", "printf", "basename", "dirname", "realpath",
+	"which", "true", "false", "test", "[",
+}
+
+// defaultArgumentRules match single letter options both on their own and
+// combined with others, like -o and -no for sort.
+var defaultArgumentRules = []*models.BashCommandRule{
+	{Command: "sed", Args: []string{"-i*", "-[a-zA-Z]*i*", "--i*"}, Action: models.BashPolicyDeny},
+	{Command: "find", Args: []string{"-exec", "-execdir", "-ok", "-okdir", "-delete", "-fprint*", "-fls"}, Action: models.BashPolicyDeny},
+	{Command: "awk", Args: []string{"*|*", "*>*", "-[dEfilopW]*", "-[a-zA-Z]*[dEfilopW]*", "--de*", "--du*", "--ex*", "--fi*", "--in*", "--lo*", "--pr*"}, Action: models.BashPolicyAsk},
+	{Command: "rg", Args: []string{"--pre*"}, Action: models.BashPolicyDeny},
+	{Command: "sort", Args: []string{"--co*"}, Action: models.BashPolicyDeny},
+	{Command: "sort", Args: []string{"-o*", "-[a-zA-Z]*o*", "--o*"}, Action: models.BashPolicyAsk},
+	{Command: "tree", Args: []string{"-[oR]*", "-[a-zA-Z]*[oR]*"}, Action: models.BashPolicyAsk},
+	{Command: "file", Args: []string{"-C*", "-[a-zA-Z]*C*", "--co*"}, Action: models.BashPolicyAsk},
+}
+
+var braceExpansion = regexp.MustCompile(`\{[^{}]*(,|\.\.)[^{}]*\}`)
+
+var safeRedirectTargets = []string{"/dev/null", "/dev/stdout", "/dev/stderr"}
+
+// DefaultPolicy allows the read-only commands the bash tool used to be limited
+// to, denies their options that write files or run other commands, and asks
+// about everything else.
+func DefaultPolicy(workspacePath string) *models.BashPolicy {
+	rules := make([]*models.BashCommandRule, 0, len(readOnlyCommands)+len(defaultArgumentRules))
+	for _, command := range readOnlyCommands {
+		rules = append(rules, &models.BashCommandRule{Command: command, Action: models.BashPolicyAllow})
+	}
+	for _, rule := range defaultArgumentRules {
+		rules = append(rules, &models.BashCommandRule{
+			Command: rule.Command,
+			Args:    append([]string(nil), rule.Args...),
+			Action:  rule.Action,
+		})
+	}
+
+	return &models.BashPolicy{
+		WorkspacePath: workspacePath,
+		DefaultAction: models.BashPolicyAsk,
+		Rules:         rules,
+	}
+}
+
+func WithPolicy(ctx context.Context, policy *models.BashPolicy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+func PolicyFromContext(ctx context.Context) *models.BashPolicy {
+	if policy, ok := ctx.Value(policyKey{}).(*models.BashPolicy); ok && policy != nil {
+		return policy
+	}
+
+	return DefaultPolicy("")
+}
+
+// WithApproval marks the call as approved by the user, so commands the policy
+// asks about may run.
+func WithApproval(ctx context.Context) context.Context {
+	return context.WithValue(ctx, approvalKey{}, true)
+}
+
+func isApproved(ctx context.Context) bool {
+	approved, _ := ctx.Value(approvalKey{}).(bool)
+	return approved
+}
+
+// CheckCommand parses command as bash and checks every command it would run,
+// including those in pipelines, lists, subshells and substitutions. Variable
+// assignments are asked about, since variables like PATH or LD_PRELOAD change
+// what the commands run. The most restrictive action wins.
+func CheckCommand(policy *models.BashPolicy, command string) (*Decision, error) {
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse command: %w", err)
+	}
+
+	c := &policyChecker{policy: policy, action: models.BashPolicyAllow}
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			if n.Background || n.Coprocess {
+				c.raise(models.BashPolicyAsk, "runs a command in the background")
+			}
+		case *syntax.CoprocClause:
+			c.raise(models.BashPolicyAsk, "runs a command in the background")
+		case *syntax.Redirect:
+			c.checkRedirect(n)
+		case *syntax.CallExpr:
+			c.checkCall(n)
+		case *syntax.Assign:
+			if n.Name != nil {
+				c.raise(models.BashPolicyAsk, fmt.Sprintf("sets %s", n.Name.Value))
+			}
+		case *syntax.DeclClause:
+			c.raise(models.BashPolicyAsk, fmt.Sprintf("%s needs approval", n.Variant.Value))
+		case *syntax.LetClause:
+			c.raise(models.BashPolicyAsk, "let needs approval")
+		case *syntax.WordIter:
+			c.raise(models.BashPolicyAsk, fmt.Sprintf("sets %s", n.Name.Value))
+		case *syntax.BinaryArithm:
+			if isArithmAssign(n.Op) {
+				c.raise(models.BashPolicyAsk, "assigns a variable in arithmetic")
+			}
+		case *syntax.UnaryArithm:
+			if n.Op == syntax.Inc || n.Op == syntax.Dec {
+				c.raise(models.BashPolicyAsk, "assigns a variable in arithmetic")
+			}
+		case *syntax.ParamExp:
+			if n.Exp != nil && (n.Exp.Op == syntax.AssignUnset || n.Exp.Op == syntax.AssignUnsetOrNull) {
+				c.raise(models.BashPolicyAsk, fmt.Sprintf("sets %s", n.Param.Value))
+			}
+		case *syntax.UnaryTest:
+			if n.Op == syntax.TsVarSet || n.Op == syntax.TsRefVar {
+				c.raise(models.BashPolicyAsk, fmt.Sprintf("[[ %s ]] looks up a variable by name", n.Op))
+			}
+		case *syntax.BinaryTest:
+			if isArithmTest(n.Op) && (!isIntegerOperand(n.X) || !isIntegerOperand(n.Y)) {
+				c.raise(models.BashPolicyAsk, fmt.Sprintf("[[ %s ]] evaluates its operands as arithmetic", n.Op))
+			}
+		}
+		return true
+	})
+
+	return &Decision{Action: c.action, Reason: strings.Join(c.reasons, "; ")}, nil
+}
+
+type policyChecker struct {
+	policy  *models.BashPolicy
+	action  models.BashPolicyAction
+	reasons []string
+}
+
+func (c *policyChecker) raise(action models.BashPolicyAction, reason string) {
+	switch {
+	case actionRank(action) > actionRank(c.action):
+		c.action = action
+		c.reasons = []string{reason}
+	case action == c.action && action != models.BashPolicyAllow:
+		c.reasons = append(c.reasons, reason)
+	}
+}
+
+func (c *policyChecker) checkCall(call *syntax.CallExpr) {
+	if len(call.Args) == 0 {
+		return
+	}
+
+	name, ok := literalWord(call.Args[0])
+	if !ok || name == "" {
+		c.raise(models.BashPolicyAsk, fmt.Sprintf("runs a command whose name is only known at run time: %s", wordValue(call.Args[0])))
+		return
+	}
+
+	args := make([]string, 0, len(call.Args)-1)
+	for _, arg := range call.Args[1:] {
+		value := wordValue(arg)
+		if _, ok := literalWord(arg); !ok {
+			c.raise(models.BashPolicyAsk, fmt.Sprintf("%s has an argument only known at run time: %s", name, value))
+		}
+		args = append(args, value)
+	}
+
+	// Rules match the command name, so a command run by path is held to the
+	// rules for its base name but is never allowed without approval.
+	base := path.Base(name)
+	action, subject := c.commandAction(base, args)
+	if base != name && action == models.BashPolicyAllow {
+		action, subject = models.BashPolicyAsk, name
+	}
+	switch action {
+	case models.BashPolicyDeny:
+		c.raise(action, fmt.Sprintf("%s is denied", subject))
+	case models.BashPolicyAsk:
+		c.raise(action, fmt.Sprintf("%s needs approval", subject))
+	}
+
+	if check, ok := commandChecks[base]; ok {
+		if action, reason := check(args); action != "" {
+			c.raise(action, reason)
+		}
+	}
+}
+
+// commandAction prefers the rules whose argument patterns match over the plain
+// rules for the command, and falls back to the default action.
+func (c *policyChecker) commandAction(name string, args []string) (models.BashPolicyAction, string) {
+	var plain, matched models.BashPolicyAction
+	subject := name
+	for _, rule := range c.policy.Rules {
+		if rule == nil || !matchPattern(rule.Command, name) {
+			continue
+		}
+		if len(rule.Args) == 0 {
+			plain = rule.Action
+			continue
+		}
+		for _, arg := range args {
+			if matchAnyPattern(rule.Args, arg) && actionRank(rule.Action) > actionRank(matched) {
+				matched = rule.Action
+				subject = name + " " + arg
+				break
+			}
+		}
+	}
+
+	switch {
+	case matched != "":
+		return matched, subject
+	case plain != "":
+		return plain, name
+	case c.policy.DefaultAction != "":
+		return c.policy.DefaultAction, name
+	default:
+		return models.BashPolicyAsk, name
+	}
+}
+
+func (c *policyChecker) checkRedirect(redirect *syntax.Redirect) {
+	target := ""
+	if redirect.Word != nil {
+		target = wordValue(redirect.Word)
+	}
+
+	switch redirect.Op {
+	case syntax.DplOut:
+		if target == "-" || strings.Trim(target, "0123456789") == "" {
+			return
+		}
+	case syntax.RdrOut, syntax.AppOut, syntax.RdrAll, syntax.AppAll, syntax.ClbOut, syntax.RdrInOut:
+	default:
+		return
+	}
+
+	for _, safe := range safeRedirectTargets {
+		if target == safe {
+			return
+		}
+	}
+	c.raise(models.BashPolicyAsk, fmt.Sprintf("writes to %s", target))
+}
+
+func isArithmAssign(op syntax.BinAritOperator) bool {
+	switch op {
+	case syntax.Assgn, syntax.AddAssgn, syntax.SubAssgn, syntax.MulAssgn, syntax.QuoAssgn, syntax.RemAssgn,
+		syntax.AndAssgn, syntax.OrAssgn, syntax.XorAssgn, syntax.ShlAssgn, syntax.ShrAssgn:
+		return true
+	default:
+		return false
+	}
+}
+
+func isArithmTest(op syntax.BinTestOperator) bool {
+	switch op {
+	case syntax.TsEql, syntax.TsNeq, syntax.TsLeq, syntax.TsGeq, syntax.TsLss, syntax.TsGtr:
+		return true
+	default:
+		return false
+	}
+}
+
+// isIntegerOperand reports whether a [[ ]] operand is a plain number. Other
+// operands are evaluated as arithmetic, which can name array elements whose
+// subscripts run commands.
+func isIntegerOperand(expr syntax.TestExpr) bool {
+	word, ok := expr.(*syntax.Word)
+	if !ok {
+		return false
+	}
+	value, ok := literalWord(word)
+	if !ok {
+		return false
+	}
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+
+	return value != "" && strings.Trim(value, "0123456789") == ""
+}
+
+func actionRank(action models.BashPolicyAction) int {
+	switch action {
+	case models.BashPolicyAllow:
+		return 1
+	case models.BashPolicyAsk:
+		return 2
+	case models.BashPolicyDeny:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// wordValue is the value of a word after quote removal. Parts that are only
+// known at run time, like expansions, are kept in their source form.
+func wordValue(word *syntax.Word) string {
+	var b strings.Builder
+	writeWordParts(&b, word.Parts)
+	return b.String()
+}
+
+// literalWord is the value of a word that has no expansions. Brace
+// expansions, globs that look like options and ANSI-C quoted strings count as
+// expansions, since their value is not the text the policy would see.
+func literalWord(word *syntax.Word) (string, bool) {
+	var unquoted strings.Builder
+	for _, part := range word.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			unquoted.WriteString(p.Value)
+		case *syntax.SglQuoted:
+			if p.Dollar {
+				return "", false
+			}
+			unquoted.WriteString("_")
+		case *syntax.DblQuoted:
+			for _, inner := range p.Parts {
+				if _, ok := inner.(*syntax.Lit); !ok {
+					return "", false
+				}
+			}
+			unquoted.WriteString("_")
+		default:
+			return "", false
+		}
+	}
+
+	value := wordValue(word)
+	if braceExpansion.MatchString(unquoted.String()) {
+		return "", false
+	}
+	if strings.HasPrefix(value, "-") && strings.ContainsAny(unquoted.String(), "*?[") {
+		return "", false
+	}
+
+	return value, true
+}
+
+func writeWordParts(b *strings.Builder, parts []syntax.WordPart) {
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(unescape(p.Value))
+		case *syntax.SglQuoted:
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			writeWordParts(b, p.Parts)
+		default:
+			printer := syntax.NewPrinter()
+			printer.Print(b, part)
+		}
+	}
+}
+
+func unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func matchAnyPattern(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPattern matches value against a shell style pattern where * and ? also
+// match slashes, unlike path.Match.
+func matchPattern(pattern string, value string) bool {
+	runes := []rune(pattern)
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := slices.Index(runes[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := string(runes[i+1 : i+1+end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return pattern == value
+	}
+
+	return re.MatchString(value)
+}
@@ -0,0 +1,231 @@
+package bash
+
+import (
+	"testing"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+type policyCase struct {
+	command string
+	action  models.BashPolicyAction
+}
+
+func checkPolicyCases(t *testing.T, cases []policyCase) {
+	t.Helper()
+
+	policy := DefaultPolicy("")
+	for _, c := range cases {
+		decision, err := CheckCommand(policy, c.command)
+		if err != nil {
+			t.Errorf("%s: %v", c.command, err)
+			continue
+		}
+		if decision.Action != c.action {
+			t.Errorf("%s: got %s (%s), want %s", c.command, decision.Action, decision.Reason, c.action)
+		}
+	}
+}
+
+func TestCheckCommandVariableNames(t *testing.T) {
+	checkPolicyCases(t, []policyCase{
+		{`printf '%s\n' x`, models.BashPolicyAllow},
+		{`printf -v 'x[$(id)]' y`, models.BashPolicyAsk},
+		{`printf -vx y`, models.BashPolicyAsk},
+		{`printf -- -v`, models.BashPolicyAllow},
+		{`test -f go.mod`, models.BashPolicyAllow},
+		{`test -v 'a[$(id)]'`, models.BashPolicyAsk},
+		{`test ! -R 'a[$(id)]'`, models.BashPolicyAsk},
+		{`[ -v 'a[$(id)]' ]`, models.BashPolicyAsk},
+		{`[[ -f go.mod ]]`, models.BashPolicyAllow},
+		{`[[ -v 'a[$(id)]' ]]`, models.BashPolicyAsk},
+		{`[[ 1 -eq 2 ]]`, models.BashPolicyAllow},
+		{`[[ 'a[$(id)]' -eq 1 ]]`, models.BashPolicyAsk},
+	})
+}
+
+func TestCheckCommandAssignments(t *testing.T) {
+	checkPolicyCases(t, []policyCase{
+		{`ls`, models.BashPolicyAllow},
+		{`PATH=. ls`, models.BashPolicyAsk},
+		{`LD_PRELOAD=./x.so cat f`, models.BashPolicyAsk},
+		{`BASH_ENV=./x cat f`, models.BashPolicyAsk},
+		{`PATH=.; ls`, models.BashPolicyAsk},
+		{`export PATH=.; ls`, models.BashPolicyAsk},
+		{`declare -x PATH=.`, models.BashPolicyAsk},
+		{`readonly IFS`, models.BashPolicyAsk},
+		{`local x=1`, models.BashPolicyAsk},
+		{`for PATH in .; do ls; done`, models.BashPolicyAsk},
+		{`let PATH=0`, models.BashPolicyAsk},
+		{`echo $((PATH=0))`, models.BashPolicyAsk},
+		{`(( 1 + 2 ))`, models.BashPolicyAllow},
+		{`cat < ${PATH:=.}`, models.BashPolicyAsk},
+	})
+}
+
+func TestCheckCommandWriteOptions(t *testing.T) {
+	checkPolicyCases(t, []policyCase{
+		{`sort f`, models.BashPolicyAllow},
+		{`sort -o out f`, models.BashPolicyAsk},
+		{`grep x f | sort -o out`, models.BashPolicyAsk},
+		{`sort -no out f`, models.BashPolicyAsk},
+		{`sort --output=out f`, models.BashPolicyAsk},
+		{`sort --compress-program=sh f`, models.BashPolicyDeny},
+		{`tree -a`, models.BashPolicyAllow},
+		{`tree -o out`, models.BashPolicyAsk},
+		{`tree -ao out`, models.BashPolicyAsk},
+		{`tree -HR x`, models.BashPolicyAsk},
+		{`file go.mod`, models.BashPolicyAllow},
+		{`file -C -m magic`, models.BashPolicyAsk},
+		{`awk -F, '{print $1}' f`, models.BashPolicyAllow},
+		{`awk -f prog.awk f`, models.BashPolicyAsk},
+		{`awk -o out '{print}' f`, models.BashPolicyAsk},
+		{`awk -p '{print}' f`, models.BashPolicyAsk},
+		{`awk -bd '{print}' f`, models.BashPolicyAsk},
+		{`awk --profile '{print}' f`, models.BashPolicyAsk},
+		{`sed -n 1p f`, models.BashPolicyAllow},
+		{`sed -i s/a/b/ f`, models.BashPolicyDeny},
+		{`sed -ni s/a/b/ f`, models.BashPolicyDeny},
+		{`sed --in-place s/a/b/ f`, models.BashPolicyDeny},
+	})
+}
+
+func TestCheckCommand(t *testing.T) {
+	checkPolicyCases(t, []policyCase{
+		{`ls -la`, models.BashPolicyAllow},
+		{`grep -rn foo . | head -5`, models.BashPolicyAllow},
+		{`cat a && wc -l b || true`, models.BashPolicyAllow},
+		{`(cd sub; ls)`, models.BashPolicyAsk},
+		{`rm -rf build`, models.BashPolicyAsk},
+		{`ls $(rm -rf build)`, models.BashPolicyAsk},
+		{`echo "$(id)"`, models.BashPolicyAsk},
+		{`/bin/ls`, models.BashPolicyAsk},
+		{`./ls`, models.BashPolicyAsk},
+		{`$cmd`, models.BashPolicyAsk},
+		{`ls $dir`, models.BashPolicyAsk},
+		{`ls {a,b}`, models.BashPolicyAsk},
+		{`ls -*`, models.BashPolicyAsk},
+		{`ls *.go`, models.BashPolicyAllow},
+		{`echo $'\x41'`, models.BashPolicyAsk},
+		{`find . -name '*.go'`, models.BashPolicyAllow},
+		{`find . -delete`, models.BashPolicyDeny},
+		{`find . -exec rm {} +`, models.BashPolicyDeny},
+		{`rg --pre cat foo`, models.BashPolicyDeny},
+		{`rm x; find . -delete`, models.BashPolicyDeny},
+	})
+}
+
+func TestCheckCommandRedirects(t *testing.T) {
+	checkPolicyCases(t, []policyCase{
+		{`ls > /dev/null`, models.BashPolicyAllow},
+		{`ls 2>&1`, models.BashPolicyAllow},
+		{`ls 2>/dev/stderr`, models.BashPolicyAllow},
+		{`cat < in`, models.BashPolicyAllow},
+		{`cat <<< hi`, models.BashPolicyAllow},
+		{`ls > out`, models.BashPolicyAsk},
+		{`ls >> out`, models.BashPolicyAsk},
+		{`ls &> out`, models.BashPolicyAsk},
+		{`ls >| out`, models.BashPolicyAsk},
+		{`cat <> f`, models.BashPolicyAsk},
+		{`ls >&out`, models.BashPolicyAsk},
+		{`ls > $f`, models.BashPolicyAsk},
+	})
+}
+
+func TestCheckCommandScripts(t *testing.T) {
+	checkPolicyCases(t, []policyCase{
+		{`sed -n '/foo/,/bar/p' f`, models.BashPolicyAllow},
+		{`sed 's/a/b/g;s|c|d|' f`, models.BashPolicyAllow},
+		{`sed -e 's/a/b/' -e '1d' f`, models.BashPolicyAllow},
+		{`sed 'w out' f`, models.BashPolicyDeny},
+		{`sed '1W out' f`, models.BashPolicyDeny},
+		{`sed 's/a/b/w out' f`, models.BashPolicyDeny},
+		{`sed 's/a/b/e' f`, models.BashPolicyDeny},
+		{`sed '1e id' f`, models.BashPolicyDeny},
+		{`sed --expression='/x/ { w out' -e '}' f`, models.BashPolicyDeny},
+		{`sed -f script.sed f`, models.BashPolicyAsk},
+		{`sed 's/a/b' f`, models.BashPolicyAsk},
+		{`awk '{print $1}' f`, models.BashPolicyAllow},
+		{`awk 'BEGIN { system("id") }'`, models.BashPolicyDeny},
+		{`awk 'BEGIN { system ("id") }'`, models.BashPolicyDeny},
+		{`awk '{print | "sh"}' f`, models.BashPolicyAsk},
+		{`awk '{print > "out"}' f`, models.BashPolicyAsk},
+		{`awk '{"id" | getline x}' f`, models.BashPolicyAsk},
+		{`uniq -c f`, models.BashPolicyAllow},
+		{`uniq -f 1 f`, models.BashPolicyAllow},
+		{`uniq --skip-fields 1 f`, models.BashPolicyAllow},
+		{`uniq f out`, models.BashPolicyDeny},
+		{`uniq -c -- f out`, models.BashPolicyDeny},
+	})
+}
+
+func TestCheckCommandBackground(t *testing.T) {
+	checkPolicyCases(t, []policyCase{
+		{`ls &`, models.BashPolicyAsk},
+		{`ls & wait`, models.BashPolicyAsk},
+		{`coproc cat f`, models.BashPolicyAsk},
+		{`coproc worker { cat f; }`, models.BashPolicyAsk},
+	})
+}
+
+func TestCheckCommandRules(t *testing.T) {
+	policy := &models.BashPolicy{
+		DefaultAction: models.BashPolicyDeny,
+		Rules: []*models.BashCommandRule{
+			{Command: "go", Action: models.BashPolicyAllow},
+			{Command: "go", Args: []string{"run"}, Action: models.BashPolicyAsk},
+			{Command: "git", Args: []string{"push*"}, Action: models.BashPolicyDeny},
+			{Command: "git", Action: models.BashPolicyAsk},
+		},
+	}
+
+	cases := []policyCase{
+		{`go test ./...`, models.BashPolicyAllow},
+		{`go run .`, models.BashPolicyAsk},
+		{`git status`, models.BashPolicyAsk},
+		{`git push origin`, models.BashPolicyDeny},
+		{`make`, models.BashPolicyDeny},
+		{`go test ./... && make`, models.BashPolicyDeny},
+	}
+	for _, c := range cases {
+		decision, err := CheckCommand(policy, c.command)
+		if err != nil {
+			t.Errorf("%s: %v", c.command, err)
+			continue
+		}
+		if decision.Action != c.action {
+			t.Errorf("%s: got %s (%s), want %s", c.command, decision.Action, decision.Reason, c.action)
+		}
+	}
+
+	if _, err := CheckCommand(policy, `ls 'unterminated`); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{"ls", "ls", true},
+		{"ls", "lsof", false},
+		{"git*", "git-lfs", true},
+		{"*", "a/b/c", true},
+		{"-?", "-i", true},
+		{"-?", "-in", false},
+		{"-i*", "-i.bak", true},
+		{"-[a-zA-Z]*i*", "-ni", true},
+		{"-[a-zA-Z]*i*", "--in-place", false},
+		{"[!-]*", "x", true},
+		{"[!-]*", "-x", false},
+		{"a.b", "axb", false},
+		{"[abc", "[abc", true},
+	}
+	for _, c := range cases {
+		if got := matchPattern(c.pattern, c.value); got != c.match {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", c.pattern, c.value, got, c.match)
+		}
+	}
+}
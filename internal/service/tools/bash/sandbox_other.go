@@ -0,0 +1,12 @@
+//go:build !linux
+
+package bash
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+func startSandboxed(cmd *exec.Cmd, workspace string) error {
+	return fmt.Errorf("sandbox is only supported on Linux")
+}
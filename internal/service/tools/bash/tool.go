@@ -12,12 +12,12 @@ import (
 
 const (
 	BashToolName        = "bash"
-	BashToolDescription = "Executes a single-line bash command and returns the combined output with the exit code. Supported commands: ls, tree, rg, grep, cat, head, tail, sed, awk. Shell operators (|, &, ;, >, <, `, $()) are not supported."
+	BashToolDescription = "Executes a bash command in the workspace and returns the combined output with the exit code. Pipelines, lists and substitutions are supported. Every command in it is checked against the workspace bash policy: read-only commands such as ls, rg, grep, cat, head, tail, sed and awk run directly, options that write files or run other commands (sed -i, find -exec) are refused, and other commands or redirections into files need the user's approval."
 )
 
 type BashParams struct {
 	Command        string `json:"command" jsonschema:"description=The bash command to execute."`
-	WorkDir        string `json:"work_dir" jsonschema:"description=The absolute path of the directory to run the command in."`
+	WorkDir        string `json:"work_dir,omitempty" jsonschema:"description=The directory to run the command in, relative to the workspace root or an absolute path inside the workspace. Defaults to the workspace root."`
 	TimeoutSeconds int    `json:"timeout_seconds,omitempty" jsonschema:"description=Maximum execution time in seconds. If the value is less than or equal to 0, it defaults to 10 seconds."`
 }
 
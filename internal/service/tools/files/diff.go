@@ -0,0 +1,246 @@
+package files
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+const (
+	diffContextLines = 3
+	maxDiffTraceSize = 32 * 1024 * 1024
+)
+
+type lineEdit struct {
+	kind models.DiffLineKind
+	text string
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+func buildFileDiff(path string, operation models.FileDiffOperation, before string, after string) *models.FileDiff {
+	edits := diffLines(splitLines(before), splitLines(after))
+
+	diff := &models.FileDiff{
+		Path:      path,
+		Operation: operation,
+		Hunks:     buildHunks(edits),
+	}
+	for _, edit := range edits {
+		switch edit.kind {
+		case models.DiffLineAdd:
+			diff.Additions += 1
+		case models.DiffLineDelete:
+			diff.Deletions += 1
+		}
+	}
+
+	return diff
+}
+
+// diffLines is Myers' diff over lines. When the files are so different that
+// the trace would get too large, it falls back to replacing everything.
+func diffLines(a []string, b []string) []lineEdit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix += 1
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix += 1
+	}
+
+	edits := make([]lineEdit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, lineEdit{kind: models.DiffLineContext, text: line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, lineEdit{kind: models.DiffLineContext, text: line})
+	}
+
+	return edits
+}
+
+func myers(a []string, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD == 0 {
+		return nil
+	}
+
+	offset := maxD
+	v := make([]int, 2*maxD+2)
+	var trace [][]int
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		if (d+1)*len(v) > maxDiffTraceSize {
+			return replaceAll(a, b)
+		}
+		trace = append(trace, slices.Clone(v))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x += 1
+				y += 1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var edits []lineEdit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, lineEdit{kind: models.DiffLineContext, text: a[x-1]})
+			x -= 1
+			y -= 1
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, lineEdit{kind: models.DiffLineAdd, text: b[y-1]})
+			} else {
+				edits = append(edits, lineEdit{kind: models.DiffLineDelete, text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(edits)
+
+	return edits
+}
+
+func replaceAll(a []string, b []string) []lineEdit {
+	edits := make([]lineEdit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, lineEdit{kind: models.DiffLineDelete, text: line})
+	}
+	for _, line := range b {
+		edits = append(edits, lineEdit{kind: models.DiffLineAdd, text: line})
+	}
+
+	return edits
+}
+
+func buildHunks(edits []lineEdit) []*models.DiffHunk {
+	hunks := []*models.DiffHunk{}
+
+	var hunk *models.DiffHunk
+	oldLine, newLine := 1, 1
+	lastChange := -1
+	for i, edit := range edits {
+		if edit.kind != models.DiffLineContext {
+			if hunk == nil || i-lastChange > 2*diffContextLines {
+				start := max(i-diffContextLines, lastChange+1, 0)
+				if hunk != nil {
+					closeHunk(hunk, lastChange, edits)
+				}
+				hunk = &models.DiffHunk{
+					OldStart: oldLine - (i - start),
+					NewStart: newLine - (i - start),
+				}
+				for _, context := range edits[start:i] {
+					appendHunkLine(hunk, context)
+				}
+				hunks = append(hunks, hunk)
+			} else {
+				for _, context := range edits[lastChange+1 : i] {
+					appendHunkLine(hunk, context)
+				}
+			}
+			appendHunkLine(hunk, edit)
+			lastChange = i
+		}
+
+		if edit.kind != models.DiffLineAdd {
+			oldLine += 1
+		}
+		if edit.kind != models.DiffLineDelete {
+			newLine += 1
+		}
+	}
+	if hunk != nil {
+		closeHunk(hunk, lastChange, edits)
+	}
+
+	for _, h := range hunks {
+		if h.OldLines == 0 {
+			h.OldStart -= 1
+		}
+		if h.NewLines == 0 {
+			h.NewStart -= 1
+		}
+	}
+
+	return hunks
+}
+
+func closeHunk(hunk *models.DiffHunk, lastChange int, edits []lineEdit) {
+	end := min(lastChange+1+diffContextLines, len(edits))
+	for _, context := range edits[lastChange+1 : end] {
+		appendHunkLine(hunk, context)
+	}
+}
+
+func appendHunkLine(hunk *models.DiffHunk, edit lineEdit) {
+	hunk.Lines = append(hunk.Lines, &models.DiffLine{
+		Kind:    edit.kind,
+		Content: strings.TrimSuffix(edit.text, "\n"),
+	})
+
+	switch edit.kind {
+	case models.DiffLineContext:
+		hunk.OldLines += 1
+		hunk.NewLines += 1
+	case models.DiffLineAdd:
+		hunk.NewLines += 1
+	case models.DiffLineDelete:
+		hunk.OldLines += 1
+	}
+}
+
+func formatDiffSummary(diff *models.FileDiff) string {
+	verb := "Updated"
+	switch diff.Operation {
+	case models.FileDiffOperationCreate:
+		verb = "Created"
+	case models.FileDiffOperationDelete:
+		verb = "Deleted"
+	}
+
+	return fmt.Sprintf("%s %s (+%d -%d)", verb, diff.Path, diff.Additions, diff.Deletions)
+}
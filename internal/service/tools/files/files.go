@@ -0,0 +1,230 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+const defaultFileMode = 0o644
+
+type fileChange struct {
+	path      string
+	content   string
+	operation models.FileDiffOperation
+	diff      *models.FileDiff
+}
+
+func WriteFile(ctx context.Context, params *WriteFileParams) (string, error) {
+	if params == nil {
+		return "", fmt.Errorf("params must be provided")
+	}
+
+	path, err := tools.ResolveWorkspacePath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	before, exists, err := readTextFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	operation := models.FileDiffOperationUpdate
+	if !exists {
+		operation = models.FileDiffOperationCreate
+	}
+
+	return commitChanges(ctx, []*fileChange{newFileChange(ctx, path, operation, before, params.Content)})
+}
+
+func EditFile(ctx context.Context, params *EditFileParams) (string, error) {
+	if params == nil {
+		return "", fmt.Errorf("params must be provided")
+	}
+	if params.OldString == "" {
+		return "", fmt.Errorf("old_string must be provided, use write_file to create a file")
+	}
+	if params.OldString == params.NewString {
+		return "", fmt.Errorf("old_string and new_string are the same")
+	}
+
+	path, err := tools.ResolveWorkspacePath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	before, exists, err := readTextFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("file does not exist: %s", params.Path)
+	}
+
+	count := strings.Count(before, params.OldString)
+	switch {
+	case count == 0:
+		return "", fmt.Errorf("old_string was not found in %s", params.Path)
+	case count > 1 && !params.ReplaceAll:
+		return "", fmt.Errorf("old_string occurs %d times in %s, add more context or set replace_all", count, params.Path)
+	}
+
+	after := strings.Replace(before, params.OldString, params.NewString, 1)
+	if params.ReplaceAll {
+		after = strings.ReplaceAll(before, params.OldString, params.NewString)
+	}
+
+	return commitChanges(ctx, []*fileChange{newFileChange(ctx, path, models.FileDiffOperationUpdate, before, after)})
+}
+
+// ApplyPatch checks every file of the patch before writing any of them, so a
+// patch that fails half way does not leave the workspace half patched.
+func ApplyPatch(ctx context.Context, params *ApplyPatchParams) (string, error) {
+	if params == nil {
+		return "", fmt.Errorf("params must be provided")
+	}
+	if strings.TrimSpace(params.Patch) == "" {
+		return "", fmt.Errorf("patch must be provided")
+	}
+
+	patches, err := parsePatch(params.Patch)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	changes := make([]*fileChange, 0, len(patches))
+	seen := make(map[string]struct{}, len(patches))
+	for _, p := range patches {
+		path, err := tools.ResolveWorkspacePath(ctx, p.path())
+		if err != nil {
+			return "", err
+		}
+		if _, ok := seen[path]; ok {
+			return "", fmt.Errorf("patch changes %s more than once", p.path())
+		}
+		seen[path] = struct{}{}
+
+		before, exists, err := readTextFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case p.oldPath == devNull:
+			if exists {
+				return "", fmt.Errorf("file already exists: %s", p.path())
+			}
+			after, err := applyHunks("", p.hunks)
+			if err != nil {
+				return "", fmt.Errorf("failed to patch %s: %w", p.path(), err)
+			}
+			changes = append(changes, newFileChange(ctx, path, models.FileDiffOperationCreate, "", after))
+		case !exists:
+			return "", fmt.Errorf("file does not exist: %s", p.path())
+		case p.newPath == devNull:
+			if len(p.hunks) > 0 {
+				if _, err := applyHunks(before, p.hunks); err != nil {
+					return "", fmt.Errorf("failed to patch %s: %w", p.path(), err)
+				}
+			}
+			changes = append(changes, newFileChange(ctx, path, models.FileDiffOperationDelete, before, ""))
+		default:
+			if p.oldPath != p.newPath {
+				return "", fmt.Errorf("renaming files is not supported: %s -> %s", p.oldPath, p.newPath)
+			}
+			after, err := applyHunks(before, p.hunks)
+			if err != nil {
+				return "", fmt.Errorf("failed to patch %s: %w", p.path(), err)
+			}
+			changes = append(changes, newFileChange(ctx, path, models.FileDiffOperationUpdate, before, after))
+		}
+	}
+
+	return commitChanges(ctx, changes)
+}
+
+func newFileChange(ctx context.Context, path string, operation models.FileDiffOperation, before string, after string) *fileChange {
+	return &fileChange{
+		path:      path,
+		content:   after,
+		operation: operation,
+		diff:      buildFileDiff(tools.WorkspaceRelPath(ctx, path), operation, before, after),
+	}
+}
+
+// commitChanges records the diffs for the caller and writes the files unless
+// this is a dry run.
+func commitChanges(ctx context.Context, changes []*fileChange) (string, error) {
+	recorder := tools.FileChangesFromContext(ctx)
+	dryRun := recorder != nil && recorder.DryRun
+
+	summaries := make([]string, 0, len(changes))
+	for _, change := range changes {
+		if !dryRun {
+			if err := writeChange(change); err != nil {
+				return "", err
+			}
+		}
+		if recorder != nil {
+			recorder.Record(change.diff)
+		}
+		summaries = append(summaries, formatDiffSummary(change.diff))
+	}
+
+	return strings.Join(summaries, "\n"), nil
+}
+
+func writeChange(change *fileChange) error {
+	if change.operation == models.FileDiffOperationDelete {
+		if err := os.Remove(change.path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", change.diff.Path, err)
+		}
+		return nil
+	}
+
+	mode := fs.FileMode(defaultFileMode)
+	if info, err := os.Stat(change.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(change.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", change.diff.Path, err)
+	}
+	if err := os.WriteFile(change.path, []byte(change.content), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", change.diff.Path, err)
+	}
+
+	return nil
+}
+
+func readTextFile(path string) (string, bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", false, fmt.Errorf("path is not a regular file: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !utf8.Valid(data) {
+		return "", false, fmt.Errorf("file is not valid UTF-8 text: %s", path)
+	}
+
+	return string(data), true, nil
+}
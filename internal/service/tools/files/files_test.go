@@ -0,0 +1,240 @@
+package files
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+func newWorkspace(t *testing.T, files map[string]string) (context.Context, string) {
+	t.Helper()
+
+	workDir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(workDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return tools.WithWorkDir(context.Background(), workDir), workDir
+}
+
+func expectFile(t *testing.T, workDir string, name string, want string) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(workDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Fatalf("%s: got %q, want %q", name, data, want)
+	}
+}
+
+func expectNoFile(t *testing.T, workDir string, name string) {
+	t.Helper()
+
+	if _, err := os.Stat(filepath.Join(workDir, name)); !os.IsNotExist(err) {
+		t.Fatalf("%s: expected no file, got %v", name, err)
+	}
+}
+
+func TestApplyPatchFiles(t *testing.T) {
+	ctx, workDir := newWorkspace(t, map[string]string{
+		"a.txt":     "one\ntwo\nthree\n",
+		"sub/b.txt": "keep\nold\n",
+		"gone.txt":  "bye\n",
+	})
+
+	_, err := ApplyPatch(ctx, &ApplyPatchParams{Patch: `--- a/a.txt
++++ b/a.txt
+@@ -2 +2 @@
+-two
++TWO
+--- a/sub/b.txt
++++ b/sub/b.txt
+@@ -1,2 +1,2 @@
+ keep
+-old
++new
+--- /dev/null
++++ b/dir/c.txt
+@@ -0,0 +1 @@
++created
+--- a/gone.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectFile(t, workDir, "a.txt", "one\nTWO\nthree\n")
+	expectFile(t, workDir, "sub/b.txt", "keep\nnew\n")
+	expectFile(t, workDir, "dir/c.txt", "created\n")
+	expectNoFile(t, workDir, "gone.txt")
+}
+
+func TestApplyPatchRejectsWholePatch(t *testing.T) {
+	cases := map[string]string{
+		"a later hunk fails": `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-missing
++found
+`,
+		"a created file exists": `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+--- /dev/null
++++ b/b.txt
+@@ -0,0 +1 @@
++new
+`,
+		"a changed file is missing": `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+--- a/c.txt
++++ b/c.txt
+@@ -1 +1 @@
+-x
++y
+`,
+		"a deleted file does not match": `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+--- a/b.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-other
+`,
+		"a file is changed twice": `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++uno
+`,
+		"a path leaves the workspace": `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+--- /dev/null
++++ b/../escape.txt
+@@ -0,0 +1 @@
++out
+`,
+	}
+
+	for name, patch := range cases {
+		ctx, workDir := newWorkspace(t, map[string]string{
+			"a.txt": "one\n",
+			"b.txt": "two\n",
+		})
+		if _, err := ApplyPatch(ctx, &ApplyPatchParams{Patch: patch}); err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+		expectFile(t, workDir, "a.txt", "one\n")
+		expectFile(t, workDir, "b.txt", "two\n")
+	}
+}
+
+func TestApplyPatchDryRun(t *testing.T) {
+	ctx, workDir := newWorkspace(t, map[string]string{"a.txt": "one\n"})
+	ctx, changes := tools.WithFileChanges(ctx, true)
+
+	summary, err := ApplyPatch(ctx, &ApplyPatchParams{Patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+ONE\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(summary, "a.txt") {
+		t.Fatalf("summary does not name the file: %q", summary)
+	}
+	if diffs := changes.Diffs(); len(diffs) != 1 || diffs[0].Path != "a.txt" {
+		t.Fatalf("recorded diffs: %+v", diffs)
+	}
+	expectFile(t, workDir, "a.txt", "one\n")
+}
+
+func TestEditFile(t *testing.T) {
+	cases := []struct {
+		name    string
+		params  EditFileParams
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "unique",
+			params: EditFileParams{Path: "a.txt", OldString: "beta", NewString: "BETA"},
+			want:   "alpha BETA gamma gamma\n",
+		},
+		{
+			name:    "ambiguous",
+			params:  EditFileParams{Path: "a.txt", OldString: "gamma", NewString: "GAMMA"},
+			wantErr: "occurs 2 times",
+		},
+		{
+			name:   "replace all",
+			params: EditFileParams{Path: "a.txt", OldString: "gamma", NewString: "GAMMA", ReplaceAll: true},
+			want:   "alpha beta GAMMA GAMMA\n",
+		},
+		{
+			name:    "not found",
+			params:  EditFileParams{Path: "a.txt", OldString: "delta", NewString: "DELTA"},
+			wantErr: "was not found",
+		},
+		{
+			name:    "missing file",
+			params:  EditFileParams{Path: "b.txt", OldString: "alpha", NewString: "ALPHA"},
+			wantErr: "does not exist",
+		},
+		{
+			name:    "same strings",
+			params:  EditFileParams{Path: "a.txt", OldString: "alpha", NewString: "alpha"},
+			wantErr: "are the same",
+		},
+	}
+
+	for _, c := range cases {
+		ctx, workDir := newWorkspace(t, map[string]string{"a.txt": "alpha beta gamma gamma\n"})
+		_, err := EditFile(ctx, &c.params)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: got error %v, want %q", c.name, err, c.wantErr)
+			}
+			expectFile(t, workDir, "a.txt", "alpha beta gamma gamma\n")
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		expectFile(t, workDir, "a.txt", c.want)
+	}
+}
@@ -0,0 +1,356 @@
+package files
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+const defaultGlobLimit = 1000
+
+func Glob(ctx context.Context, params *GlobParams) (string, error) {
+	if params == nil {
+		return "", fmt.Errorf("params must be provided")
+	}
+
+	pattern := strings.TrimPrefix(strings.TrimSpace(params.Pattern), "./")
+	if pattern == "" {
+		return "", fmt.Errorf("pattern must be provided")
+	}
+	if err := validateGlob(pattern); err != nil {
+		return "", err
+	}
+
+	root, err := tools.ResolveWorkspacePath(ctx, pathOrRoot(params.Path))
+	if err != nil {
+		return "", err
+	}
+
+	limit := params.Limit
+	if limit <= 0 || limit > defaultGlobLimit {
+		limit = defaultGlobLimit
+	}
+
+	var matches []string
+	truncated := false
+	err = walkWorkspace(ctx, root, func(path string, rel string, entry fs.DirEntry) error {
+		if !matchGlob(pattern, rel) {
+			return nil
+		}
+		if len(matches) == limit {
+			truncated = true
+			return fs.SkipAll
+		}
+
+		display := tools.WorkspaceRelPath(ctx, path)
+		if entry.IsDir() {
+			display += "/"
+		}
+		matches = append(matches, display)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "No files matched.", nil
+	}
+	result := strings.Join(matches, "\n")
+	if truncated {
+		result += fmt.Sprintf("\n(results truncated at %d entries, narrow the pattern or path)", limit)
+	}
+
+	return result, nil
+}
+
+// walkWorkspace walks root in lexical order and calls fn for every entry that
+// is not ignored, with rel being its slash separated path relative to root.
+// Symlinks are reported but never followed.
+func walkWorkspace(ctx context.Context, root string, fn func(path string, rel string, entry fs.DirEntry) error) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", tools.WorkspaceRelPath(ctx, root), err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("path is not a directory: %s", tools.WorkspaceRelPath(ctx, root))
+	}
+
+	ignore := newIgnoreMatcher(ctx)
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+
+		if entry.IsDir() && entry.Name() == ".git" {
+			return fs.SkipDir
+		}
+		if ignore.ignored(path, entry.IsDir()) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		return fn(path, filepath.ToSlash(rel), entry)
+	})
+	if err != nil && !errors.Is(err, fs.SkipAll) {
+		return fmt.Errorf("failed to walk %s: %w", tools.WorkspaceRelPath(ctx, root), err)
+	}
+
+	return nil
+}
+
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher applies the .gitignore files from the workspace root down to
+// the directory of a path, with deeper files taking precedence.
+type ignoreMatcher struct {
+	roots []string
+	rules map[string][]ignoreRule
+}
+
+func newIgnoreMatcher(ctx context.Context) *ignoreMatcher {
+	workDir := tools.WorkDirFromContext(ctx)
+	return &ignoreMatcher{
+		roots: []string{tools.EvalSymlinksOrSelf(workDir), workDir},
+		rules: make(map[string][]ignoreRule),
+	}
+}
+
+func (m *ignoreMatcher) ignored(path string, isDir bool) bool {
+	root := ""
+	for _, r := range m.roots {
+		if r != "" && tools.IsWithin(r, path) {
+			root = r
+			break
+		}
+	}
+	if root == "" {
+		return false
+	}
+
+	var dirs []string
+	for dir := filepath.Dir(path); tools.IsWithin(root, dir); dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == root {
+			break
+		}
+	}
+
+	ignored := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, rule := range m.load(dirs[i]) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if matchGlob(rule.pattern, rel) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	return ignored
+}
+
+func (m *ignoreMatcher) load(dir string) []ignoreRule {
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []ignoreRule
+	if file, err := os.Open(filepath.Join(dir, ".gitignore")); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+				rules = append(rules, rule)
+			}
+		}
+		file.Close()
+	}
+	m.rules[dir] = rules
+
+	return rules
+}
+
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	if strings.Contains(line, "/") {
+		rule.pattern = strings.TrimPrefix(line, "/")
+	} else {
+		rule.pattern = "**/" + line
+	}
+	if validateGlob(rule.pattern) != nil {
+		return ignoreRule{}, false
+	}
+
+	return rule, true
+}
+
+func validateGlob(pattern string) error {
+	for _, expanded := range expandBraces(pattern) {
+		for _, segment := range strings.Split(expanded, "/") {
+			if segment == "**" {
+				continue
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern: %s", pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchGlob matches a slash separated path against a pattern where ** spans
+// any number of directories and {a,b} picks one of the alternatives.
+func matchGlob(pattern string, name string) bool {
+	nameParts := strings.Split(name, "/")
+	for _, expanded := range expandBraces(negateClasses(pattern)) {
+		if matchSegments(strings.Split(expanded, "/"), nameParts) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for len(pattern) > 1 && pattern[1] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// negateClasses rewrites [!abc] to the [^abc] path.Match understands.
+func negateClasses(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			b.WriteString(pattern[i : i+2])
+			i++
+		case pattern[i] == '[' && i+1 < len(pattern) && pattern[i+1] == '!':
+			b.WriteString("[^")
+			i++
+		default:
+			b.WriteByte(pattern[i])
+		}
+	}
+
+	return b.String()
+}
+
+func expandBraces(pattern string) []string {
+	start := strings.Index(pattern, "{")
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	depth := 0
+	var options []string
+	last := start + 1
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth += 1
+		case ',':
+			if depth == 1 {
+				options = append(options, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			depth -= 1
+			if depth == 0 {
+				options = append(options, pattern[last:i])
+				var expanded []string
+				for _, option := range options {
+					expanded = append(expanded, expandBraces(pattern[:start]+option+pattern[i+1:])...)
+				}
+				return expanded
+			}
+		}
+	}
+
+	return []string{pattern}
+}
+
+func pathOrRoot(value string) string {
+	if value = strings.TrimSpace(value); value != "" {
+		return value
+	}
+
+	return "."
+}
@@ -0,0 +1,193 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c/main.go", true},
+		{"**/*.go", "a/b/main.ts", false},
+		{"src/**", "src/a/b", true},
+		{"src/**", "lib/a", false},
+		{"src/**/test/*.go", "src/test/a.go", true},
+		{"src/**/test/*.go", "src/x/y/test/a.go", true},
+		{"src/**/test/*.go", "src/x/test/y/a.go", false},
+		{"**/**/*.md", "docs/readme.md", true},
+		{"*.{ts,tsx}", "app.tsx", true},
+		{"*.{ts,tsx}", "app.js", false},
+		{"{src,lib}/**/*.go", "lib/x/a.go", true},
+		{"{a,{b,c}}.txt", "c.txt", true},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"[abc].go", "b.go", true},
+		{"[!abc].go", "b.go", false},
+		{"[!abc].go", "d.go", true},
+		{`\[!abc].go`, "[!abc].go", true},
+	}
+	for _, c := range cases {
+		if got := matchGlob(c.pattern, c.name); got != c.match {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", c.pattern, c.name, got, c.match)
+		}
+	}
+}
+
+func TestParseIgnoreRule(t *testing.T) {
+	cases := []struct {
+		line string
+		rule ignoreRule
+		ok   bool
+	}{
+		{"", ignoreRule{}, false},
+		{"# comment", ignoreRule{}, false},
+		{"*.log", ignoreRule{pattern: "**/*.log"}, true},
+		{"!keep.log", ignoreRule{pattern: "**/keep.log", negate: true}, true},
+		{`\#file`, ignoreRule{pattern: "**/#file"}, true},
+		{"build/", ignoreRule{pattern: "**/build", dirOnly: true}, true},
+		{"/root.txt", ignoreRule{pattern: "root.txt"}, true},
+		{"docs/*.md  ", ignoreRule{pattern: "docs/*.md"}, true},
+		{"/", ignoreRule{}, false},
+		{"[", ignoreRule{}, false},
+	}
+	for _, c := range cases {
+		rule, ok := parseIgnoreRule(c.line)
+		if ok != c.ok || rule != c.rule {
+			t.Errorf("parseIgnoreRule(%q) = %+v, %v, want %+v, %v", c.line, rule, ok, c.rule, c.ok)
+		}
+	}
+}
+
+func TestGlobIgnore(t *testing.T) {
+	ctx, _ := newWorkspace(t, map[string]string{
+		".gitignore":           "*.log\n!keep.log\nbuild/\n/root.txt\ndocs/*.md\n",
+		"a.log":                "",
+		"keep.log":             "",
+		"root.txt":             "",
+		"build/out.txt":        "",
+		"docs/guide.md":        "",
+		"docs/api/ref.md":      "",
+		"sub/root.txt":         "",
+		"sub/build":            "a file, not a directory",
+		"sub/.gitignore":       "local.txt\n!a.log\n",
+		"sub/a.log":            "",
+		"sub/local.txt":        "",
+		"sub/deep/local.txt":   "",
+		"other/local.txt":      "",
+		".git/config":          "",
+		"src/main.go":          "",
+		"src/internal/util.go": "",
+	})
+
+	result, err := Glob(ctx, &GlobParams{Pattern: "**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		".gitignore",
+		"docs/",
+		"docs/api/",
+		"docs/api/ref.md",
+		"keep.log",
+		"other/",
+		"other/local.txt",
+		"src/",
+		"src/internal/",
+		"src/internal/util.go",
+		"src/main.go",
+		"sub/",
+		"sub/.gitignore",
+		"sub/a.log",
+		"sub/build",
+		"sub/deep/",
+		"sub/root.txt",
+	}
+	if got := strings.Split(result, "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", result, strings.Join(want, "\n"))
+	}
+}
+
+func TestGlobPatterns(t *testing.T) {
+	ctx, _ := newWorkspace(t, map[string]string{
+		"main.go":          "",
+		"cmd/tool/main.go": "",
+		"web/app.ts":       "",
+		"web/view.tsx":     "",
+	})
+
+	cases := []struct {
+		params GlobParams
+		want   string
+	}{
+		{GlobParams{Pattern: "*.go"}, "main.go"},
+		{GlobParams{Pattern: "**/*.go"}, "cmd/tool/main.go\nmain.go"},
+		{GlobParams{Pattern: "./web/*.{ts,tsx}"}, "web/app.ts\nweb/view.tsx"},
+		{GlobParams{Pattern: "*.go", Path: "cmd/tool"}, "cmd/tool/main.go"},
+		{GlobParams{Pattern: "**/*.go", Limit: 1}, "cmd/tool/main.go\n(results truncated at 1 entries, narrow the pattern or path)"},
+		{GlobParams{Pattern: "*.rs"}, "No files matched."},
+	}
+	for _, c := range cases {
+		got, err := Glob(ctx, &c.params)
+		if err != nil {
+			t.Errorf("%+v: %v", c.params, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%+v: got %q, want %q", c.params, got, c.want)
+		}
+	}
+
+	if _, err := Glob(ctx, &GlobParams{Pattern: "[", Path: "."}); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+}
+
+func TestSymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("needle\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, workDir := newWorkspace(t, map[string]string{"inside.txt": "needle\n"})
+	if err := os.Symlink(outside, filepath.Join(workDir, "linkdir")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(workDir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Search(ctx, &SearchParams{Pattern: "needle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "inside.txt:1:needle" {
+		t.Fatalf("search followed a symlink out of the workspace: %q", result)
+	}
+
+	result, err = Glob(ctx, &GlobParams{Pattern: "**/*.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(result, "secret.txt") {
+		t.Fatalf("glob followed a symlink out of the workspace: %q", result)
+	}
+
+	for _, path := range []string{"linkdir", "link.txt", "../"} {
+		if _, err := Search(ctx, &SearchParams{Pattern: "needle", Path: path}); err == nil {
+			t.Errorf("search in %s: expected an outside the workspace error", path)
+		}
+		if _, err := Glob(ctx, &GlobParams{Pattern: "*", Path: path}); err == nil {
+			t.Errorf("glob in %s: expected an outside the workspace error", path)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const devNull = "/dev/null"
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []*patchHunk
+}
+
+type patchHunk struct {
+	oldStart int
+	oldLines []string
+	newLines []string
+}
+
+func parsePatch(patch string) ([]*filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+
+	var patches []*filePatch
+	var current *filePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			current = &filePatch{
+				oldPath: patchPath(line[4:]),
+				newPath: patchPath(lines[i+1][4:]),
+			}
+			patches = append(patches, current)
+			i += 1
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", i+1)
+			}
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			current.hunks = append(current.hunks, hunk)
+			i = next - 1
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("patch has no file headers")
+	}
+	for _, p := range patches {
+		if p.oldPath == devNull && p.newPath == devNull {
+			return nil, fmt.Errorf("patch file has no path")
+		}
+		if len(p.hunks) == 0 && p.newPath != devNull {
+			return nil, fmt.Errorf("patch for %s has no hunks", p.path())
+		}
+	}
+
+	return patches, nil
+}
+
+func parseHunk(lines []string, start int) (*patchHunk, int, error) {
+	match := hunkHeaderPattern.FindStringSubmatch(lines[start])
+	if match == nil {
+		return nil, 0, fmt.Errorf("line %d: invalid hunk header: %s", start+1, lines[start])
+	}
+
+	oldStart, _ := strconv.Atoi(match[1])
+	oldCount, newCount := 1, 1
+	if match[2] != "" {
+		oldCount, _ = strconv.Atoi(match[2])
+	}
+	if match[4] != "" {
+		newCount, _ = strconv.Atoi(match[4])
+	}
+
+	hunk := &patchHunk{oldStart: oldStart}
+	i := start + 1
+	for ; i < len(lines) && (len(hunk.oldLines) < oldCount || len(hunk.newLines) < newCount); i++ {
+		line := lines[i]
+		if line == "" {
+			line = " "
+		}
+
+		switch line[0] {
+		case ' ':
+			hunk.oldLines = append(hunk.oldLines, line[1:])
+			hunk.newLines = append(hunk.newLines, line[1:])
+		case '-':
+			hunk.oldLines = append(hunk.oldLines, line[1:])
+		case '+':
+			hunk.newLines = append(hunk.newLines, line[1:])
+		case '\\':
+		default:
+			return nil, 0, fmt.Errorf("line %d: unexpected line in hunk: %s", i+1, line)
+		}
+	}
+	if len(hunk.oldLines) != oldCount || len(hunk.newLines) != newCount {
+		return nil, 0, fmt.Errorf("line %d: hunk does not match its header counts", start+1)
+	}
+	for i < len(lines) && strings.HasPrefix(lines[i], "\\") {
+		i += 1
+	}
+
+	return hunk, i, nil
+}
+
+func patchPath(header string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	if path == devNull {
+		return path
+	}
+	if rest, ok := strings.CutPrefix(path, "a/"); ok {
+		return rest
+	}
+	if rest, ok := strings.CutPrefix(path, "b/"); ok {
+		return rest
+	}
+
+	return path
+}
+
+func (p *filePatch) path() string {
+	if p.newPath != devNull {
+		return p.newPath
+	}
+
+	return p.oldPath
+}
+
+// applyHunks applies hunks in order. A hunk whose context moved is looked up
+// nearest to where the header says it should be, like patch(1) without fuzz.
+func applyHunks(content string, hunks []*patchHunk) (string, error) {
+	lines := splitLines(content)
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\n")
+	}
+
+	offset := 0
+	for n, hunk := range hunks {
+		base := hunk.oldStart - 1
+		if len(hunk.oldLines) == 0 {
+			base = hunk.oldStart
+		}
+
+		pos := findHunk(lines, hunk.oldLines, min(max(base+offset, 0), len(lines)))
+		if pos < 0 {
+			return "", fmt.Errorf("hunk %d does not match the file", n+1)
+		}
+
+		updated := make([]string, 0, len(lines)-len(hunk.oldLines)+len(hunk.newLines))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, hunk.newLines...)
+		updated = append(updated, lines[pos+len(hunk.oldLines):]...)
+		lines = updated
+		offset = pos - base + len(hunk.newLines) - len(hunk.oldLines)
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	result := strings.Join(lines, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+
+	return result, nil
+}
+
+func findHunk(lines []string, old []string, want int) int {
+	matches := func(pos int) bool {
+		if pos < 0 || pos+len(old) > len(lines) {
+			return false
+		}
+		for i, line := range old {
+			if strings.TrimSuffix(lines[pos+i], "\r") != strings.TrimSuffix(line, "\r") {
+				return false
+			}
+		}
+		return true
+	}
+
+	for delta := 0; delta <= len(lines); delta++ {
+		if matches(want - delta) {
+			return want - delta
+		}
+		if delta > 0 && matches(want+delta) {
+			return want + delta
+		}
+	}
+
+	return -1
+}
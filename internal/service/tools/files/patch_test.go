@@ -0,0 +1,164 @@
+package files
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	patches, err := parsePatch(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
+
+@@ -10 +10,2 @@
+ func f() {}
++func g() {}
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++one
++two
+--- a/old.txt
++++ /dev/null
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 3 {
+		t.Fatalf("got %d file patches, want 3", len(patches))
+	}
+
+	main := patches[0]
+	if main.oldPath != "main.go" || main.newPath != "main.go" || len(main.hunks) != 2 {
+		t.Fatalf("main.go patch: %+v", main)
+	}
+	first := main.hunks[0]
+	if first.oldStart != 1 ||
+		strings.Join(first.oldLines, "|") != "package main|var x = 1|" ||
+		strings.Join(first.newLines, "|") != "package main|var x = 2|" {
+		t.Fatalf("first hunk: %+v", first)
+	}
+	if second := main.hunks[1]; second.oldStart != 10 || len(second.oldLines) != 1 || len(second.newLines) != 2 {
+		t.Fatalf("second hunk: %+v", second)
+	}
+
+	if created := patches[1]; created.oldPath != devNull || created.path() != "new.txt" {
+		t.Fatalf("created file patch: %+v", created)
+	}
+	if deleted := patches[2]; deleted.newPath != devNull || deleted.path() != "old.txt" || len(deleted.hunks) != 0 {
+		t.Fatalf("deleted file patch: %+v", deleted)
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	cases := map[string]string{
+		"no headers":        "@@ -1 +1 @@\n-a\n+b\n",
+		"no hunks":          "--- a/f\n+++ b/f\n",
+		"no path":           "--- /dev/null\n+++ /dev/null\n",
+		"bad hunk header":   "--- a/f\n+++ b/f\n@@ -x +1 @@\n-a\n+b\n",
+		"short hunk":        "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-a\n+b",
+		"unexpected line":   "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-a\n*b\n+b\n+c\n",
+		"hunk before files": "junk\n@@ -1 +1 @@\n-a\n+b\n",
+	}
+	for name, patch := range cases {
+		if _, err := parsePatch(patch); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	content := "a\nb\nc\nd\ne\n"
+	cases := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{
+			name:  "in place",
+			patch: "@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n",
+			want:  "a\nb\nC\nd\ne\n",
+		},
+		{
+			name:  "moved down",
+			patch: "@@ -1,2 +1,2 @@\n-d\n+D\n e\n",
+			want:  "a\nb\nc\nD\ne\n",
+		},
+		{
+			name:  "moved up",
+			patch: "@@ -40,2 +40,2 @@\n-a\n+A\n b\n",
+			want:  "A\nb\nc\nd\ne\n",
+		},
+		{
+			name:  "insert",
+			patch: "@@ -5,0 +6 @@\n+f\n",
+			want:  "a\nb\nc\nd\ne\nf\n",
+		},
+		{
+			name:  "later hunks follow the offset of earlier ones",
+			patch: "@@ -1 +1,3 @@\n-a\n+a\n+a1\n+a2\n@@ -4 +6 @@\n-d\n+D\n",
+			want:  "a\na1\na2\nb\nc\nD\ne\n",
+		},
+	}
+	for _, c := range cases {
+		patches, err := parsePatch("--- a/f\n+++ b/f\n" + c.patch)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		got, err := applyHunks(content, patches[0].hunks)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestApplyHunksNearestMatch(t *testing.T) {
+	content := "x\nfoo\nx\nx\nx\nx\nfoo\nx\n"
+	patches, err := parsePatch("--- a/f\n+++ b/f\n@@ -6 +6 @@\n-foo\n+bar\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := applyHunks(content, patches[0].hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "x\nfoo\nx\nx\nx\nx\nbar\nx\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestApplyHunksMismatch(t *testing.T) {
+	patches, err := parsePatch("--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-x\n+y\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := applyHunks("a\nb\n", patches[0].hunks); err == nil {
+		t.Fatal("expected a context mismatch")
+	}
+}
+
+func TestApplyHunksTrailingNewline(t *testing.T) {
+	patches, err := parsePatch("--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := applyHunks("a", patches[0].hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "b" {
+		t.Fatalf("got %q, want %q", got, "b")
+	}
+}
@@ -0,0 +1,121 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+const (
+	defaultReadLimit = 2000
+	maxLineLength    = 2000
+	binarySniffSize  = 8000
+)
+
+func ReadFile(ctx context.Context, params *ReadFileParams) (string, error) {
+	if params == nil {
+		return "", fmt.Errorf("params must be provided")
+	}
+
+	path, err := tools.ResolveWorkspacePath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", params.Path, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("path is a directory, use glob to list it: %s", params.Path)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("path is not a regular file: %s", params.Path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", params.Path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(binarySniffSize)
+	if isBinary(head) {
+		return fmt.Sprintf("%s is a binary file (%d bytes).", tools.WorkspaceRelPath(ctx, path), info.Size()), nil
+	}
+	if len(head) == 0 {
+		return fmt.Sprintf("%s is empty.", tools.WorkspaceRelPath(ctx, path)), nil
+	}
+
+	offset := max(params.Offset, 1)
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultReadLimit
+	}
+
+	var b strings.Builder
+	lineNumber := 0
+	shown := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lineNumber += 1
+			if lineNumber >= offset && shown < limit {
+				fmt.Fprintf(&b, "%6d\t%s\n", lineNumber, truncateLine(strings.TrimRight(line, "\r\n"), maxLineLength))
+				shown += 1
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+		}
+	}
+
+	if offset > lineNumber {
+		return "", fmt.Errorf("offset %d is past the end of %s, which has %d lines", offset, params.Path, lineNumber)
+	}
+	if last := offset + shown - 1; offset > 1 || last < lineNumber {
+		fmt.Fprintf(&b, "(showing lines %d-%d of %d, use offset and limit to read more)\n", offset, last, lineNumber)
+	}
+
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// isBinary treats content with NUL bytes or broken UTF-8 as binary. The sniffed
+// prefix may end in the middle of a rune, so a short tail is tolerated.
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	for i := 0; i < utf8.UTFMax && len(head) > 0; i++ {
+		if utf8.Valid(head) {
+			return false
+		}
+		head = head[:len(head)-1]
+	}
+
+	return !utf8.Valid(head)
+}
+
+func truncateLine(line string, limit int) string {
+	if len(line) <= limit {
+		return line
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut -= 1
+	}
+
+	return line[:cut] + " ... (line truncated)"
+}
@@ -0,0 +1,211 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+	maxContextLines    = 10
+	maxSearchFileSize  = 2 * 1024 * 1024
+	maxMatchLineLength = 500
+)
+
+var fileTypes = map[string][]string{
+	"c":      {"*.c", "*.h"},
+	"cpp":    {"*.cc", "*.cpp", "*.cxx", "*.hh", "*.hpp", "*.hxx", "*.h"},
+	"css":    {"*.css", "*.scss", "*.sass", "*.less"},
+	"go":     {"*.go"},
+	"html":   {"*.html", "*.htm"},
+	"java":   {"*.java"},
+	"js":     {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+	"json":   {"*.json"},
+	"kotlin": {"*.kt", "*.kts"},
+	"md":     {"*.md", "*.markdown"},
+	"py":     {"*.py", "*.pyi"},
+	"rust":   {"*.rs"},
+	"sh":     {"*.sh", "*.bash", "*.zsh"},
+	"sql":    {"*.sql"},
+	"swift":  {"*.swift"},
+	"toml":   {"*.toml"},
+	"ts":     {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"vue":    {"*.vue"},
+	"yaml":   {"*.yaml", "*.yml"},
+}
+
+func Search(ctx context.Context, params *SearchParams) (string, error) {
+	if params == nil {
+		return "", fmt.Errorf("params must be provided")
+	}
+	if params.Pattern == "" {
+		return "", fmt.Errorf("pattern must be provided")
+	}
+
+	expr := params.Pattern
+	if params.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	filters, err := searchFilters(params)
+	if err != nil {
+		return "", err
+	}
+
+	root, err := tools.ResolveWorkspacePath(ctx, pathOrRoot(params.Path))
+	if err != nil {
+		return "", err
+	}
+
+	limit := params.MaxResults
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+	contextLines := min(max(params.ContextLines, 0), maxContextLines)
+
+	var b strings.Builder
+	matches := 0
+	truncated := false
+	search := func(path string, rel string) error {
+		if len(filters) > 0 && !slices.ContainsFunc(filters, func(filter string) bool { return matchFileFilter(filter, rel) }) {
+			return nil
+		}
+
+		lines, ok := readSearchFile(path)
+		if !ok {
+			return nil
+		}
+
+		display := tools.WorkspaceRelPath(ctx, path)
+		lastPrinted := -1
+		for i, line := range lines {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches == limit {
+				truncated = true
+				return fs.SkipAll
+			}
+			matches += 1
+
+			start := max(i-contextLines, lastPrinted+1)
+			if contextLines > 0 && b.Len() > 0 && (lastPrinted < 0 || start > lastPrinted+1) {
+				b.WriteString("--\n")
+			}
+			for j := start; j < i; j++ {
+				fmt.Fprintf(&b, "%s-%d-%s\n", display, j+1, truncateLine(lines[j], maxMatchLineLength))
+			}
+			fmt.Fprintf(&b, "%s:%d:%s\n", display, i+1, truncateLine(line, maxMatchLineLength))
+			lastPrinted = i
+
+			for j := i + 1; j <= min(i+contextLines, len(lines)-1) && !re.MatchString(lines[j]); j++ {
+				fmt.Fprintf(&b, "%s-%d-%s\n", display, j+1, truncateLine(lines[j], maxMatchLineLength))
+				lastPrinted = j
+			}
+		}
+		return nil
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", tools.WorkspaceRelPath(ctx, root), err)
+	}
+	if info.Mode().IsRegular() {
+		filters = nil
+		if err := search(root, path.Base(root)); err != nil && !errors.Is(err, fs.SkipAll) {
+			return "", err
+		}
+	} else {
+		err = walkWorkspace(ctx, root, func(path string, rel string, entry fs.DirEntry) error {
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			return search(path, rel)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if matches == 0 {
+		return "No matches found.", nil
+	}
+	result := strings.TrimRight(b.String(), "\n")
+	if truncated {
+		result += fmt.Sprintf("\n(results truncated at %d matches, narrow the pattern or path)", limit)
+	}
+
+	return result, nil
+}
+
+func searchFilters(params *SearchParams) ([]string, error) {
+	var filters []string
+	if glob := strings.TrimSpace(params.Glob); glob != "" {
+		if err := validateGlob(glob); err != nil {
+			return nil, err
+		}
+		filters = append(filters, glob)
+	}
+
+	if fileType := strings.ToLower(strings.TrimSpace(params.Type)); fileType != "" {
+		globs, ok := fileTypes[fileType]
+		if !ok {
+			names := make([]string, 0, len(fileTypes))
+			for name := range fileTypes {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			return nil, fmt.Errorf("unknown file type %s, supported types: %s", fileType, strings.Join(names, ", "))
+		}
+		if len(filters) > 0 {
+			return nil, fmt.Errorf("glob and type cannot be used together")
+		}
+		filters = append(filters, globs...)
+	}
+
+	return filters, nil
+}
+
+// matchFileFilter matches a filter without a slash against the file name at
+// any depth, and a filter with a slash against the whole relative path.
+func matchFileFilter(filter string, rel string) bool {
+	if !strings.Contains(filter, "/") {
+		return matchGlob(filter, path.Base(rel))
+	}
+
+	return matchGlob(filter, rel)
+}
+
+func readSearchFile(path string) ([]string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileSize {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || isBinary(data[:min(len(data), binarySniffSize)]) {
+		return nil, false
+	}
+	if len(data) == 0 {
+		return nil, true
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), true
+}
@@ -0,0 +1,98 @@
+package files
+
+import (
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	ctx, _ := newWorkspace(t, map[string]string{
+		".gitignore":       "vendor/\n",
+		"main.go":          "package main\n\nfunc main() {\n\tTODO()\n}\n",
+		"web/app.ts":       "// todo: types\nexport {}\n",
+		"web/app.test.ts":  "// TODO tests\n",
+		"vendor/lib.go":    "// TODO vendored\n",
+		"bin/tool":         "TODO\x00binary",
+		"notes/a/todo.txt": "one TODO\ntwo\nthree TODO\n",
+	})
+
+	cases := []struct {
+		name   string
+		params SearchParams
+		want   string
+	}{
+		{
+			name:   "all files",
+			params: SearchParams{Pattern: "TODO"},
+			want:   "main.go:4:\tTODO()\nnotes/a/todo.txt:1:one TODO\nnotes/a/todo.txt:3:three TODO\nweb/app.test.ts:1:// TODO tests",
+		},
+		{
+			name:   "ignore case",
+			params: SearchParams{Pattern: "todo:", IgnoreCase: true},
+			want:   "web/app.ts:1:// todo: types",
+		},
+		{
+			name:   "glob without a slash matches names at any depth",
+			params: SearchParams{Pattern: "TODO", Glob: "*.{ts,txt}"},
+			want:   "notes/a/todo.txt:1:one TODO\nnotes/a/todo.txt:3:three TODO\nweb/app.test.ts:1:// TODO tests",
+		},
+		{
+			name:   "glob with a slash matches the path",
+			params: SearchParams{Pattern: "TODO", Glob: "notes/**/*.txt"},
+			want:   "notes/a/todo.txt:1:one TODO\nnotes/a/todo.txt:3:three TODO",
+		},
+		{
+			name:   "type",
+			params: SearchParams{Pattern: "TODO", Type: "go"},
+			want:   "main.go:4:\tTODO()",
+		},
+		{
+			name:   "context",
+			params: SearchParams{Pattern: "TODO", Path: "notes", ContextLines: 1},
+			want:   "notes/a/todo.txt:1:one TODO\nnotes/a/todo.txt-2-two\nnotes/a/todo.txt:3:three TODO",
+		},
+		{
+			name:   "context before and after",
+			params: SearchParams{Pattern: "main\\(", ContextLines: 1, Type: "go"},
+			want:   "main.go-2-\nmain.go:3:func main() {\nmain.go-4-\tTODO()",
+		},
+		{
+			name:   "single file",
+			params: SearchParams{Pattern: "three", Path: "notes/a/todo.txt"},
+			want:   "notes/a/todo.txt:3:three TODO",
+		},
+		{
+			name:   "limit",
+			params: SearchParams{Pattern: "TODO", Path: "notes", MaxResults: 1},
+			want:   "notes/a/todo.txt:1:one TODO\n(results truncated at 1 matches, narrow the pattern or path)",
+		},
+		{
+			name:   "no matches",
+			params: SearchParams{Pattern: "FIXME"},
+			want:   "No matches found.",
+		},
+	}
+	for _, c := range cases {
+		got, err := Search(ctx, &c.params)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	failures := map[string]SearchParams{
+		"invalid regexp":    {Pattern: "("},
+		"unknown type":      {Pattern: "x", Type: "cobol"},
+		"glob and type":     {Pattern: "x", Glob: "*.go", Type: "go"},
+		"invalid glob":      {Pattern: "x", Glob: "["},
+		"missing path":      {Pattern: "x", Path: "missing"},
+		"path outside root": {Pattern: "x", Path: "/"},
+	}
+	for name, params := range failures {
+		if _, err := Search(ctx, &params); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
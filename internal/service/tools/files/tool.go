@@ -0,0 +1,160 @@
+package files
+
+import (
+	"context"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/service/tools"
+)
+
+const (
+	WriteFileToolName         = "write_file"
+	WriteFileToolDescription  = "Creates a file or replaces its whole content. Paths are relative to the workspace root and must stay inside the workspace."
+	EditFileToolName          = "edit_file"
+	EditFileToolDescription   = "Replaces an exact string in a file. old_string must match the file exactly, including whitespace, and must be unique unless replace_all is set."
+	ApplyPatchToolName        = "apply_patch"
+	ApplyPatchToolDescription = "Applies a unified diff to one or more files in the workspace. Use --- /dev/null to create a file and +++ /dev/null to delete one. Nothing is written unless every hunk applies."
+	ReadFileToolName          = "read_file"
+	ReadFileToolDescription   = "Reads a text file in the workspace and returns its lines prefixed with line numbers. Reads up to 2000 lines from offset, use offset and limit to page through longer files. Binary files are reported instead of read."
+	GlobToolName              = "glob"
+	GlobToolDescription       = "Lists files and directories in the workspace whose path matches a glob pattern, relative to path. Supports *, ?, [abc], {a,b} and ** for any number of directories, e.g. **/*.go. Entries ignored by .gitignore and the .git directory are skipped."
+	SearchToolName            = "search"
+	SearchToolDescription     = "Searches file contents in the workspace with a regular expression (Go RE2 syntax) and returns matching lines as path:line:text, with context lines as path-line-text. Files can be filtered with a glob or a file type. Binary files and entries ignored by .gitignore are skipped."
+)
+
+type WriteFileParams struct {
+	Path    string `json:"path" jsonschema:"description=The path of the file, relative to the workspace root."`
+	Content string `json:"content" jsonschema:"description=The full content to write."`
+}
+
+type EditFileParams struct {
+	Path       string `json:"path" jsonschema:"description=The path of the file, relative to the workspace root."`
+	OldString  string `json:"old_string" jsonschema:"description=The exact text to replace."`
+	NewString  string `json:"new_string" jsonschema:"description=The text to replace it with."`
+	ReplaceAll bool   `json:"replace_all,omitempty" jsonschema:"description=Replace every occurrence instead of requiring a unique match."`
+}
+
+type ApplyPatchParams struct {
+	Patch string `json:"patch" jsonschema:"description=The unified diff to apply, with --- and +++ file headers and @@ hunk headers."`
+}
+
+type ReadFileParams struct {
+	Path   string `json:"path" jsonschema:"description=The path of the file, relative to the workspace root."`
+	Offset int    `json:"offset,omitempty" jsonschema:"description=The 1-based line number to start reading from. Defaults to 1."`
+	Limit  int    `json:"limit,omitempty" jsonschema:"description=The maximum number of lines to read. Defaults to 2000."`
+}
+
+type GlobParams struct {
+	Pattern string `json:"pattern" jsonschema:"description=The glob pattern to match, relative to path, e.g. **/*.go or src/*.{ts,tsx}."`
+	Path    string `json:"path,omitempty" jsonschema:"description=The directory to search in, relative to the workspace root. Defaults to the workspace root."`
+	Limit   int    `json:"limit,omitempty" jsonschema:"description=The maximum number of entries to return. Defaults to 1000."`
+}
+
+type SearchParams struct {
+	Pattern      string `json:"pattern" jsonschema:"description=The regular expression to search for."`
+	Path         string `json:"path,omitempty" jsonschema:"description=The file or directory to search in, relative to the workspace root. Defaults to the workspace root."`
+	Glob         string `json:"glob,omitempty" jsonschema:"description=Only search files matching this glob. A glob without a slash matches file names at any depth, e.g. *.go."`
+	Type         string `json:"type,omitempty" jsonschema:"description=Only search files of this type, e.g. go, ts, py, rust, md."`
+	IgnoreCase   bool   `json:"ignore_case,omitempty" jsonschema:"description=Match case insensitively."`
+	ContextLines int    `json:"context_lines,omitempty" jsonschema:"description=The number of lines to show before and after each match, at most 10."`
+	MaxResults   int    `json:"max_results,omitempty" jsonschema:"description=The maximum number of matching lines to return. Defaults to 100."`
+}
+
+func GetWriteFileTool(ctx context.Context) (*schema.ToolInfo, tool.InvokableTool, error) {
+	t, err := utils.InferTool(WriteFileToolName, WriteFileToolDescription, WriteFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return info, t, nil
+}
+
+func GetEditFileTool(ctx context.Context) (*schema.ToolInfo, tool.InvokableTool, error) {
+	t, err := utils.InferTool(EditFileToolName, EditFileToolDescription, EditFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return info, t, nil
+}
+
+func GetApplyPatchTool(ctx context.Context) (*schema.ToolInfo, tool.InvokableTool, error) {
+	t, err := utils.InferTool(ApplyPatchToolName, ApplyPatchToolDescription, ApplyPatch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return info, t, nil
+}
+
+func GetReadFileTool(ctx context.Context) (*schema.ToolInfo, tool.InvokableTool, error) {
+	t, err := utils.InferTool(ReadFileToolName, ReadFileToolDescription, ReadFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return info, t, nil
+}
+
+func GetGlobTool(ctx context.Context) (*schema.ToolInfo, tool.InvokableTool, error) {
+	t, err := utils.InferTool(GlobToolName, GlobToolDescription, Glob)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return info, t, nil
+}
+
+func GetSearchTool(ctx context.Context) (*schema.ToolInfo, tool.InvokableTool, error) {
+	t, err := utils.InferTool(SearchToolName, SearchToolDescription, Search)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := t.Info(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return info, t, nil
+}
+
+func init() {
+	tools.RegisterTool(WriteFileToolName, GetWriteFileTool)
+	tools.RegisterTool(EditFileToolName, GetEditFileTool)
+	tools.RegisterTool(ApplyPatchToolName, GetApplyPatchTool)
+	tools.RegisterTool(ReadFileToolName, GetReadFileTool)
+	tools.RegisterTool(GlobToolName, GetGlobTool)
+	tools.RegisterTool(SearchToolName, GetSearchTool)
+	tools.RegisterPreviewTool(WriteFileToolName)
+	tools.RegisterPreviewTool(EditFileToolName)
+	tools.RegisterPreviewTool(ApplyPatchToolName)
+}
@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"maps"
+	"slices"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
@@ -27,8 +29,8 @@ func GetAllRegisteredTools(ctx context.Context) ([]*schema.ToolInfo, map[string]
 		return nil
 	}
 
-	for _, getToolFunc := range registeredTools {
-		if err := addTool(getToolFunc); err != nil {
+	for _, name := range slices.Sorted(maps.Keys(registeredTools)) {
+		if err := addTool(registeredTools[name]); err != nil {
 			return nil, nil, err
 		}
 	}
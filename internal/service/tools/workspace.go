@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zjregee/alter/internal/models"
+)
+
+type workDirKey struct{}
+
+type fileChangesKey struct{}
+
+type threadIDKey struct{}
+
+var previewTools = make(map[string]struct{})
+
+// FileChanges collects the diffs of one tool call. In a dry run tools record
+// their diffs without touching the disk, which is how approvals get a preview.
+type FileChanges struct {
+	DryRun bool
+
+	mu    sync.Mutex
+	diffs []*models.FileDiff
+}
+
+func WithWorkDir(ctx context.Context, workDir string) context.Context {
+	return context.WithValue(ctx, workDirKey{}, workDir)
+}
+
+func WorkDirFromContext(ctx context.Context) string {
+	workDir, _ := ctx.Value(workDirKey{}).(string)
+	return workDir
+}
+
+// WithThreadID scopes thread data, like artifacts, to the thread running the
+// tool.
+func WithThreadID(ctx context.Context, threadID string) context.Context {
+	return context.WithValue(ctx, threadIDKey{}, threadID)
+}
+
+func ThreadIDFromContext(ctx context.Context) string {
+	threadID, _ := ctx.Value(threadIDKey{}).(string)
+	return threadID
+}
+
+func WithFileChanges(ctx context.Context, dryRun bool) (context.Context, *FileChanges) {
+	changes := &FileChanges{DryRun: dryRun}
+	return context.WithValue(ctx, fileChangesKey{}, changes), changes
+}
+
+func FileChangesFromContext(ctx context.Context) *FileChanges {
+	changes, _ := ctx.Value(fileChangesKey{}).(*FileChanges)
+	return changes
+}
+
+func (c *FileChanges) Record(diffs ...*models.FileDiff) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.diffs = append(c.diffs, diffs...)
+}
+
+func (c *FileChanges) Diffs() []*models.FileDiff {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.diffs
+}
+
+// RegisterPreviewTool marks a tool whose dry run is side-effect free, so it
+// can be run before approval to show what it would change.
+func RegisterPreviewTool(name string) {
+	previewTools[name] = struct{}{}
+}
+
+func IsPreviewTool(name string) bool {
+	_, ok := previewTools[name]
+	return ok
+}
+
+// ResolveWorkspacePath turns a path given by the model into an absolute path
+// inside the workspace of ctx. Relative paths are taken from the workspace
+// root, and symlinks are resolved so a link cannot lead outside of it.
+func ResolveWorkspacePath(ctx context.Context, path string) (string, error) {
+	workDir := WorkDirFromContext(ctx)
+	if workDir == "" {
+		return "", fmt.Errorf("workspace is not available")
+	}
+
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", fmt.Errorf("path must be provided")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	path = filepath.Clean(path)
+
+	root, err := filepath.EvalSymlinks(workDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	if !IsWithin(workDir, path) && !IsWithin(root, path) {
+		return "", fmt.Errorf("path is outside the workspace: %s", path)
+	}
+
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return "", err
+	}
+	if !IsWithin(root, resolved) {
+		return "", fmt.Errorf("path is outside the workspace: %s", path)
+	}
+
+	return resolved, nil
+}
+
+// WorkspaceRelPath is the form paths are shown in, relative to the workspace
+// when possible.
+func WorkspaceRelPath(ctx context.Context, path string) string {
+	workDir := WorkDirFromContext(ctx)
+	for _, root := range []string{workDir, EvalSymlinksOrSelf(workDir)} {
+		if rel, err := filepath.Rel(root, path); err == nil && IsWithin(root, path) {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	return path
+}
+
+func IsWithin(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// resolveExisting resolves symlinks in the longest existing prefix of path and
+// appends the rest. A dangling symlink is an error since writing through it
+// would create its target wherever it points.
+func resolveExisting(path string) (string, error) {
+	rest := ""
+	current := path
+	for {
+		if _, err := os.Lstat(current); err == nil {
+			resolved, err := filepath.EvalSymlinks(current)
+			if err != nil {
+				return "", fmt.Errorf("failed to resolve %s: %w", current, err)
+			}
+			return filepath.Join(resolved, rest), nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to stat %s: %w", current, err)
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path, nil
+		}
+		rest = filepath.Join(filepath.Base(current), rest)
+		current = parent
+	}
+}
+
+// EvalSymlinksOrSelf resolves the symlinks in path, or returns path unchanged
+// when it cannot be resolved.
+func EvalSymlinksOrSelf(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+
+	return resolved
+}
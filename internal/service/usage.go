@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/zjregee/alter/internal/models"
+	"github.com/zjregee/alter/internal/service/agents/provider/usage"
+)
+
+func (a *Agent) recordUsage(modelID string, response *schema.Message) {
+	if response.ResponseMeta == nil || response.ResponseMeta.Usage == nil {
+		return
+	}
+
+	tokenUsage := response.ResponseMeta.Usage
+	turn := &models.AgentTurnUsage{
+		ModelID:            modelID,
+		PromptTokens:       tokenUsage.PromptTokens,
+		CachedPromptTokens: tokenUsage.PromptTokenDetails.CachedTokens,
+		CompletionTokens:   tokenUsage.CompletionTokens,
+		Timestamp:          time.Now().UnixMilli(),
+	}
+	if cost := usage.GetChatModelCostUSD(modelID, turn.PromptTokens, turn.CachedPromptTokens, turn.CompletionTokens); cost != nil {
+		turn.CostUSD = *cost
+		turn.Priced = true
+	}
+
+	a.mu.Lock()
+	addUsage(a.stats.Usage, turn)
+	addUsage(&a.runUsage, turn)
+	a.stats.Turns = append(a.stats.Turns, turn)
+	a.mu.Unlock()
+
+	if err := addDailyUsage(turn); err != nil {
+		fmt.Printf("Failed to record daily usage: %v\n", err)
+	}
+}
+
+func addUsage(total *models.AgentUsage, turn *models.AgentTurnUsage) {
+	total.PromptTokens += turn.PromptTokens
+	total.CachedPromptTokens += turn.CachedPromptTokens
+	total.CompletionTokens += turn.CompletionTokens
+	total.TotalTokens = total.PromptTokens + total.CompletionTokens
+	total.CostUSD += turn.CostUSD
+	if !turn.Priced {
+		total.Unpriced = true
+	}
+}
+
+func (a *Agent) UsageStats() (*models.AgentUsage, []*models.AgentTurnUsage) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	total := *a.stats.Usage
+	return &total, slices.Clone(a.stats.Turns)
+}
+
+func (s *AgentService) GetThreadStats(id string) (*models.ThreadStats, error) {
+	s.mu.RLock()
+	thread, exists := s.agents[id]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrThreadNotFound, id)
+	}
+
+	total, turns := thread.Agent.UsageStats()
+	return &models.ThreadStats{
+		ThreadID: id,
+		Usage:    total,
+		Turns:    turns,
+	}, nil
+}
@@ -9,3 +9,19 @@ import (
 func GenerateAgentID() string {
 	return fmt.Sprintf("agent-%s", utils.GenerateUUID())
 }
+
+func GenerateAttachmentID() string {
+	return fmt.Sprintf("attachment-%s", utils.GenerateUUID())
+}
+
+func GenerateMessageID() string {
+	return fmt.Sprintf("msg-%s", utils.GenerateFullUUID())
+}
+
+func GenerateScheduledJobID() string {
+	return fmt.Sprintf("job-%s", utils.GenerateUUID())
+}
+
+func GenerateQueuedInputID() string {
+	return fmt.Sprintf("input-%s", utils.GenerateUUID())
+}
@@ -74,5 +74,18 @@ func deleteWorkspace(workspacePath string) error {
 		}
 	}
 
+	if err := storage.DeleteToolApprovalSettings(workspacePath); err != nil {
+		return err
+	}
+	if err := storage.DeleteWorkspaceHooks(workspacePath); err != nil {
+		return err
+	}
+	if err := storage.DeleteBashPolicy(workspacePath); err != nil {
+		return err
+	}
+	if err := deleteWorkspaceMCPServers(workspacePath); err != nil {
+		return err
+	}
+
 	return storage.SaveWorkspaceInfos(infos.Infos)
 }
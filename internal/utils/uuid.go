@@ -10,3 +10,9 @@ func GenerateUUID() string {
 	uuidStr := uuid.New().String()
 	return strings.ReplaceAll(uuidStr, "-", "")[:8]
 }
+
+// GenerateFullUUID keeps every character of the UUID, for IDs that pile up in
+// storage and must not collide, where the short form is too easy to repeat.
+func GenerateFullUUID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
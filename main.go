@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"log"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -10,12 +11,17 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/options/mac"
 
 	"github.com/zjregee/alter/internal/app"
+	"github.com/zjregee/alter/internal/cli"
 )
 
 //go:embed all:frontend/src
 var assets embed.FS
 
 func main() {
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
 	application := app.NewApp()
 
 	err := wails.Run(&options.App{
@@ -29,6 +35,7 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 30, G: 30, B: 30, A: 255},
 		OnStartup:        application.Startup,
+		OnShutdown:       application.Shutdown,
 		Bind: []any{
 			application,
 		},